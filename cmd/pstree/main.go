@@ -0,0 +1,8 @@
+// Command pstree displays running processes as a tree
+package main
+
+import "pstree/pkg/pstree"
+
+func main() {
+	pstree.Main()
+}
@@ -0,0 +1,80 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ageFormats are the values of --age-format
+var ageFormats = []string{"elapsed", "start"}
+
+// linuxBootTime returns the boot time, from the btime line of /proc/stat,
+// read once
+var linuxBootTime = sync.OnceValues(func() (time.Time, bool) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, found := strings.CutPrefix(line, "btime "); found {
+			if seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				return time.Unix(seconds, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+})
+
+// linuxStarted converts a start time in clock ticks since boot, from
+// /proc/PID/stat, to a time
+func linuxStarted(ticks uint64) time.Time {
+	boot, ok := linuxBootTime()
+	if !ok {
+		return time.Time{}
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+}
+
+// validateAgeFormat checks the --age-format argument
+func validateAgeFormat(format string) error {
+	for _, known := range ageFormats {
+		if format == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --age-format %q, expected one of %s", format, strings.Join(ageFormats, ", "))
+}
+
+// formatElapsed formats a duration with its two largest units, e.g. 3d4h
+func formatElapsed(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	minutes := int(d/time.Minute) % 60
+	seconds := int(d/time.Second) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// ageAnnotation returns how long a process has been running, or when it
+// started with --age-format start, empty when unknown
+func (t *Tree) ageAnnotation(process Process) string {
+	if process.Started.IsZero() {
+		return ""
+	}
+	if t.config.AgeFormat == "start" {
+		return "(started " + process.Started.Format(time.DateTime) + ")"
+	}
+	return "(age " + formatElapsed(time.Since(process.Started)) + ")"
+}
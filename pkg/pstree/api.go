@@ -0,0 +1,101 @@
+package pstree
+
+import (
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// Options are the settings of the API functions, the same as the flags
+// of the pstree command, see DefaultOptions
+type Options struct {
+	Config
+	// pids to start from and patterns to search for, like the arguments
+	// of the command
+	Args []string
+	// table Render works on, nil to take a snapshot from Source
+	Processes []Process
+}
+
+// DefaultOptions returns the defaults of the pstree flags, except that
+// all processes are shown rather than those below the caller's parent
+func DefaultOptions() Options {
+	t := newTree()
+	// registering the flags sets their defaults
+	t.addTreeFlags(&cobra.Command{})
+	opts := Options{Config: t.config}
+	opts.AOption = true
+	return opts
+}
+
+// useOptions applies opts like the command line of pstree
+func (t *Tree) useOptions(opts Options) error {
+	t.config = opts.Config
+	return t.setupConfig(opts.Args)
+}
+
+// useProcesses loads a copy of list as the process table
+func (t *Tree) useProcesses(list []Process) {
+	t.procs = slices.Clone(list)
+	t.indexProcesses()
+	t.breakCycles()
+}
+
+// Snapshot reads the process table from the default source
+func Snapshot() ([]Process, error) {
+	t := newTree()
+	if err := t.useOptions(DefaultOptions()); err != nil {
+		return nil, err
+	}
+	if err := t.loadProcesses(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(t.procs), nil
+}
+
+// BuildTree returns a copy of list with the ParentIdx, ChildIdx and
+// SisterIdx links of every process set, and their birth order and
+// descendants counts
+func BuildTree(list []Process) []Process {
+	t := newTree()
+	t.useProcesses(list)
+	t.resetTree()
+	t.makeTreeHierarchy()
+	t.computeBirthOrder()
+	t.countDescendants()
+	return slices.Clone(t.procs)
+}
+
+// Filter returns a copy of list with the tree built and Print set on the
+// processes opts selects, the links skip the processes that don't print
+func Filter(list []Process, opts Options) ([]Process, error) {
+	t := newTree()
+	if err := t.useOptions(opts); err != nil {
+		return nil, err
+	}
+	t.useProcesses(list)
+	t.filterTree()
+	return slices.Clone(t.procs), nil
+}
+
+// Render writes the tree of opts.Processes, or of a fresh snapshot, as
+// the pstree command prints it. Lines are only truncated to opts.Width
+func Render(w io.Writer, opts Options) error {
+	t := newTree()
+	if err := t.useOptions(opts); err != nil {
+		return err
+	}
+	if opts.Width == 0 {
+		t.config.WOption = true
+	}
+	if opts.Processes != nil {
+		t.useProcesses(opts.Processes)
+	} else if err := t.loadProcessesSampled(); err != nil {
+		return err
+	}
+
+	t.output = w
+	_, err := t.renderProcesses(opts.Args)
+	return err
+}
@@ -0,0 +1,54 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	// deletedExeStyle and setuidStyle color the findings of --audit
+	deletedExeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
+	setuidStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+)
+
+// auditProcess flags a process running a deleted executable, i.e. an old
+// binary replaced by an upgrade, or a setuid or setgid one. The real and
+// effective ids of status catch the setuid processes whose executable
+// can't be inspected
+func auditProcess(proc *Process, procDir, status string) {
+	proc.ExeDeleted = strings.HasSuffix(proc.Exe, " (deleted)")
+	if info, err := os.Stat(filepath.Join(procDir, "exe")); err == nil {
+		proc.Setuid = info.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0
+	}
+	for _, key := range []string{"Uid", "Gid"} {
+		if ids := statusInts(status, key); len(ids) > 1 && ids[0] != ids[1] {
+			proc.Setuid = true
+		}
+	}
+}
+
+// auditStyle colors the processes flagged by --audit
+func auditStyle(process Process) (lipgloss.Style, bool) {
+	switch {
+	case process.ExeDeleted:
+		return deletedExeStyle, true
+	case process.Setuid:
+		return setuidStyle, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// auditMarkers returns the --audit findings of a process
+func auditMarkers(process Process) string {
+	var markers []string
+	if process.ExeDeleted {
+		markers = append(markers, "<deleted exe>")
+	}
+	if process.Setuid {
+		markers = append(markers, "<setuid>")
+	}
+	return strings.Join(markers, " ")
+}
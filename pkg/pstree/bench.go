@@ -0,0 +1,193 @@
+package pstree
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// synthShapes are the values of bench --shape
+var synthShapes = []string{"random", "wide", "deep", "balanced"}
+
+// synthSpec describes a synthetic process table
+type synthSpec struct {
+	// number of processes, init included
+	Procs int
+	// how parents are picked: random among the older processes, all
+	// under init, one chain, or Fanout children each
+	Shape  string
+	Fanout int
+	// deepest level below init, 0 for no limit
+	Depth int
+	Seed  int64
+}
+
+// validate checks the values given on the command line
+func (s synthSpec) validate() error {
+	if s.Procs < 1 {
+		return fmt.Errorf("--procs must be at least 1")
+	}
+	if !slices.Contains(synthShapes, s.Shape) {
+		return fmt.Errorf("unknown shape %q, expected one of %v", s.Shape, synthShapes)
+	}
+	if s.Fanout < 1 {
+		return fmt.Errorf("--fanout must be at least 1")
+	}
+	if s.Depth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+	return nil
+}
+
+// synthProcesses generates a process table of pids 1 to spec.Procs, the
+// same spec always gives the same table
+func synthProcesses(spec synthSpec) []Process {
+	rng := rand.New(rand.NewSource(spec.Seed))
+	boot := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	list := make([]Process, spec.Procs)
+	// depth below init, by pid
+	depth := make([]int, spec.Procs+1)
+	for i := range list {
+		pid := i + 1
+		ppid := 0
+		if pid > 1 {
+			switch spec.Shape {
+			case "random":
+				ppid = 1 + rng.Intn(pid-1)
+			case "wide":
+				ppid = 1
+			case "deep":
+				ppid = pid - 1
+			case "balanced":
+				ppid = (pid-2)/spec.Fanout + 1
+			}
+			for spec.Depth > 0 && depth[ppid] >= spec.Depth {
+				ppid = list[ppid-1].PPID
+			}
+			depth[pid] = depth[ppid] + 1
+		}
+
+		uid := 1000 + rng.Intn(8)
+		list[i] = Process{
+			UID:         uid,
+			PID:         pid,
+			PPID:        ppid,
+			PGID:        pid,
+			Owner:       fmt.Sprintf("user%d", uid-1000),
+			Comm:        fmt.Sprintf("worker%d", pid%97),
+			Cmd:         fmt.Sprintf("/usr/bin/worker%d --id %d --queue q%d", pid%97, pid, rng.Intn(100)),
+			ThreadCount: 1 + rng.Intn(16),
+			State:       "S",
+			Started:     boot.Add(time.Duration(pid) * time.Millisecond),
+			RSS:         uint64(1+rng.Intn(512)) << 20,
+			ParentIdx:   -1,
+			ChildIdx:    -1,
+			SisterIdx:   -1,
+		}
+	}
+	if len(list) > 0 {
+		list[0].Owner, list[0].UID, list[0].Cmd, list[0].Comm = "root", 0, "/sbin/init", "init"
+	}
+	return list
+}
+
+// benchTimes are the durations of the phases of one benchmark round
+type benchTimes struct {
+	load, build, render time.Duration
+}
+
+// benchRound loads a copy of table, builds its hierarchy and renders it
+// to io.Discard
+func (t *Tree) benchRound(table []Process) benchTimes {
+	var times benchTimes
+
+	start := time.Now()
+	t.useSnapshot(TableSnapshot{Processes: table})
+	times.load = time.Since(start)
+
+	start = time.Now()
+	t.resetTree()
+	t.makeTreeHierarchy()
+	t.computeBirthOrder()
+	times.build = time.Since(start)
+
+	start = time.Now()
+	t.output = io.Discard
+	// the synthetic tables always have a pid 1
+	t.RenderTree()
+	t.output = os.Stdout
+	times.render = time.Since(start)
+
+	return times
+}
+
+func (t *Tree) newBenchCmd() *cobra.Command {
+	var (
+		spec   synthSpec
+		rounds int
+		dump   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench [--procs N] [--shape SHAPE] [--rounds N] [--dump FILE]",
+		Short: "Time building and rendering the tree of a synthetic process table",
+		Long: `bench generates a process table of the given size and shape, then times
+loading it, building its hierarchy and rendering it, with the display flags
+of pstree. --dump saves the table instead, for 'pstree --source file:FILE'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := spec.validate(); err != nil {
+				return &usageError{err}
+			}
+			if rounds < 1 {
+				return &usageError{fmt.Errorf("--rounds must be at least 1")}
+			}
+			table := synthProcesses(spec)
+
+			if dump != "" {
+				f, err := os.Create(dump)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				return writeSnapshot(f, TableSnapshot{Time: time.Now(), Processes: table})
+			}
+
+			// the synthetic table has none of our pids or users
+			t.config.AOption = true
+			if err := t.setupConfig(nil); err != nil {
+				return &usageError{err}
+			}
+			t.CalculateTerminalWidth()
+
+			var total benchTimes
+			for range rounds {
+				times := t.benchRound(table)
+				total.load += times.load
+				total.build += times.build
+				total.render += times.render
+			}
+			n := time.Duration(rounds)
+			fmt.Printf("%d processes, %s shape, mean of %d rounds: load %v, hierarchy %v, render %v\n",
+				spec.Procs, spec.Shape, rounds, total.load/n, total.build/n, total.render/n)
+			return nil
+		},
+	}
+
+	t.addTreeFlags(cmd)
+	cmd.Flags().IntVar(&spec.Procs, "procs", 50000, "number of processes")
+	cmd.Flags().StringVar(&spec.Shape, "shape", "random", "tree shape: random, wide, deep or balanced")
+	cmd.Flags().IntVar(&spec.Fanout, "fanout", 4, "children per process with --shape balanced")
+	cmd.Flags().IntVar(&spec.Depth, "depth", 0, "deepest level below init, 0 for no limit")
+	cmd.Flags().Int64Var(&spec.Seed, "seed", 1, "seed of the generator, the same seed gives the same table")
+	cmd.Flags().IntVar(&rounds, "rounds", 5, "number of timed rounds")
+	cmd.Flags().StringVar(&dump, "dump", "", "write the table to this file as a recorded snapshot instead")
+
+	return cmd
+}
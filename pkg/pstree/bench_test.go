@@ -0,0 +1,86 @@
+package pstree
+
+import (
+	"fmt"
+	"testing"
+)
+
+// setupBenchConfig returns a tree with the flag defaults of pstree
+// bench, which renders every process
+func setupBenchConfig(tb testing.TB) *Tree {
+	tb.Helper()
+	tr := newTree()
+	tr.newBenchCmd()
+	tr.config.AOption = true
+	if err := tr.setupConfig(nil); err != nil {
+		tb.Fatal(err)
+	}
+	tr.config.Columns = 200
+	return tr
+}
+
+func TestSynthProcesses(t *testing.T) {
+	for _, shape := range synthShapes {
+		spec := synthSpec{Procs: 500, Shape: shape, Fanout: 3, Depth: 6, Seed: 7}
+		table := synthProcesses(spec)
+		if len(table) != spec.Procs {
+			t.Fatalf("%s: %d processes, want %d", shape, len(table), spec.Procs)
+		}
+
+		tr := newTree()
+		tr.procs = table
+		tr.indexProcesses()
+		tr.resetTree()
+		tr.makeTreeHierarchy()
+		for i, process := range tr.procs {
+			if i > 0 && process.ParentIdx == -1 {
+				t.Fatalf("%s: pid %d has no parent", shape, process.PID)
+			}
+			depth := 0
+			for parent := process.ParentIdx; parent != -1; parent = tr.procs[parent].ParentIdx {
+				depth++
+			}
+			if depth > spec.Depth {
+				t.Fatalf("%s: pid %d is %d levels deep, want at most %d", shape, process.PID, depth, spec.Depth)
+			}
+		}
+	}
+
+	spec := synthSpec{Procs: 100, Shape: "random", Fanout: 1, Seed: 3}
+	a, b := synthProcesses(spec), synthProcesses(spec)
+	for i := range a {
+		if a[i].PPID != b[i].PPID || a[i].Cmd != b[i].Cmd {
+			t.Fatalf("pid %d differs between two tables of the same seed", a[i].PID)
+		}
+	}
+}
+
+func BenchmarkMakeTreeHierarchy(b *testing.B) {
+	tr := setupBenchConfig(b)
+	for _, shape := range synthShapes {
+		for _, n := range []int{1000, 10000, 50000} {
+			table := synthProcesses(synthSpec{Procs: n, Shape: shape, Fanout: 4, Seed: 1})
+			b.Run(fmt.Sprintf("%s/%d", shape, n), func(b *testing.B) {
+				tr.useSnapshot(TableSnapshot{Processes: table})
+				for b.Loop() {
+					tr.resetTree()
+					tr.makeTreeHierarchy()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRenderTree(b *testing.B) {
+	tr := setupBenchConfig(b)
+	for _, shape := range synthShapes {
+		for _, n := range []int{1000, 10000, 50000} {
+			table := synthProcesses(synthSpec{Procs: n, Shape: shape, Fanout: 4, Seed: 1})
+			b.Run(fmt.Sprintf("%s/%d", shape, n), func(b *testing.B) {
+				for b.Loop() {
+					tr.benchRound(table)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pstree
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// printByUser prints the processes of the trees below roots as one
+// section per user, each user's processes forming their own forest
+func (t *Tree) printByUser(roots []int, renderer Renderer) {
+	var shown []int
+	for _, root := range roots {
+		if t.procs[root].Print {
+			shown = append(shown, t.subtreeIndices(root)...)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, idx := range shown {
+		if !t.procs[idx].Group && !t.procs[idx].Thread {
+			counts[t.procs[idx].Owner]++
+		}
+	}
+
+	// dropProcs unlinks the processes of the other users, the links are
+	// restored after each section
+	saved := slices.Clone(t.procs)
+	for n, name := range slices.Sorted(maps.Keys(counts)) {
+		for i := range t.procs {
+			t.procs[i].Print = false
+		}
+		for _, idx := range shown {
+			t.procs[idx].Print = !t.procs[idx].Group && t.procs[idx].Owner == name
+		}
+		t.dropProcs()
+
+		if n > 0 {
+			fmt.Fprintln(t.output)
+		}
+		if counts[name] == 1 {
+			fmt.Fprintf(t.output, "%s (1 process)\n", name)
+		} else {
+			fmt.Fprintf(t.output, "%s (%d processes)\n", name, counts[name])
+		}
+		for _, idx := range shown {
+			parent := t.procs[idx].ParentIdx
+			if t.procs[idx].Print && (parent == -1 || !t.procs[parent].Print) {
+				t.walkTree(idx, renderer.Node)
+			}
+		}
+		copy(t.procs, saved)
+	}
+}
@@ -0,0 +1,59 @@
+package pstree
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// capNames are the Linux capabilities by bit number, see capabilities(7)
+var capNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid",
+	"cap_setpcap", "cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// statusCaps reads a capability mask of /proc/PID/status, e.g. CapEff
+func statusCaps(status, key string) uint64 {
+	for _, line := range strings.Split(status, "\n") {
+		if value, found := strings.CutPrefix(line, key+":"); found {
+			mask, _ := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			return mask
+		}
+	}
+	return 0
+}
+
+// capsSummary condenses a capability mask to the names of its
+// capabilities, or when most are set to "all" followed by the missing
+// ones, e.g. "all,-cap_sys_resource"
+func capsSummary(mask uint64) string {
+	known := uint64(1)<<len(capNames) - 1
+	if bits.OnesCount64(mask&known) > len(capNames)/2 {
+		return strings.Join(append([]string{"all"}, capList(known&^mask, "-")...), ",")
+	}
+	return strings.Join(capList(mask, ""), ",")
+}
+
+// capList names the capabilities of a mask, with a prefix
+func capList(mask uint64, prefix string) []string {
+	var names []string
+	for mask != 0 {
+		bit := bits.TrailingZeros64(mask)
+		mask &^= 1 << bit
+		if bit < len(capNames) {
+			names = append(names, prefix+capNames[bit])
+		} else {
+			names = append(names, prefix+"cap_"+strconv.Itoa(bit))
+		}
+	}
+	return names
+}
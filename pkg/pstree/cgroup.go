@@ -0,0 +1,242 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupMount = "/sys/fs/cgroup"
+)
+
+// parseCgroupFile parses the content of /proc/PID/cgroup into a
+// controller -> path map. The unified (v2) hierarchy is stored under ""
+func parseCgroupFile(data string) map[string]string {
+	cgroups := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			cgroups[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			cgroups[controller] = parts[2]
+		}
+	}
+	return cgroups
+}
+
+// cgroupV2Mount returns where the unified hierarchy is mounted, either
+// directly on /sys/fs/cgroup or under unified/ on hybrid systems
+func cgroupV2Mount() string {
+	if _, err := os.Stat(filepath.Join(cgroupMount, "cgroup.controllers")); err == nil {
+		return cgroupMount
+	}
+	return filepath.Join(cgroupMount, "unified")
+}
+
+// readCgroupFile reads a single line control file, walking up the cgroup
+// hierarchy until a level that has the file is found
+func readCgroupFile(mount, cgroupPath, name string) (string, bool) {
+	for p := cgroupPath; ; p = path.Dir(p) {
+		if data, err := os.ReadFile(filepath.Join(mount, p, name)); err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+		if p == "/" || p == "." {
+			return "", false
+		}
+	}
+}
+
+// cgroupCPUs returns the effective cpu quota of a cgroup, in CPUs, taking
+// the tightest limit of the cgroup and its ancestors. 0 means unlimited
+func cgroupCPUs(p Process) float64 {
+	var limit float64
+	tighten := func(quota, period float64) {
+		if quota > 0 && period > 0 && (limit == 0 || quota/period < limit) {
+			limit = quota / period
+		}
+	}
+
+	if cgroupPath, ok := p.Cgroups[""]; ok {
+		mount := cgroupV2Mount()
+		for dir := cgroupPath; ; dir = path.Dir(dir) {
+			if data, err := os.ReadFile(filepath.Join(mount, dir, "cpu.max")); err == nil {
+				// "max 100000" or "200000 100000"
+				fields := strings.Fields(string(data))
+				if len(fields) == 2 && fields[0] != "max" {
+					quota, _ := strconv.ParseFloat(fields[0], 64)
+					period, _ := strconv.ParseFloat(fields[1], 64)
+					tighten(quota, period)
+				}
+			}
+			if dir == "/" || dir == "." {
+				break
+			}
+		}
+	}
+
+	if cgroupPath, ok := p.Cgroups["cpu"]; ok {
+		mount := filepath.Join(cgroupMount, "cpu")
+		for dir := cgroupPath; ; dir = path.Dir(dir) {
+			quotaData, err1 := os.ReadFile(filepath.Join(mount, dir, "cpu.cfs_quota_us"))
+			periodData, err2 := os.ReadFile(filepath.Join(mount, dir, "cpu.cfs_period_us"))
+			if err1 == nil && err2 == nil {
+				quota, _ := strconv.ParseFloat(strings.TrimSpace(string(quotaData)), 64)
+				period, _ := strconv.ParseFloat(strings.TrimSpace(string(periodData)), 64)
+				tighten(quota, period)
+			}
+			if dir == "/" || dir == "." {
+				break
+			}
+		}
+	}
+
+	return limit
+}
+
+// cgroupCPUSet returns the cpus a cgroup is allowed to run on, e.g. "0-3"
+func cgroupCPUSet(p Process) string {
+	if cgroupPath, ok := p.Cgroups[""]; ok {
+		if cpus, ok := readCgroupFile(cgroupV2Mount(), cgroupPath, "cpuset.cpus.effective"); ok {
+			return cpus
+		}
+	}
+	if cgroupPath, ok := p.Cgroups["cpuset"]; ok {
+		mount := filepath.Join(cgroupMount, "cpuset")
+		if cpus, ok := readCgroupFile(mount, cgroupPath, "cpuset.effective_cpus"); ok {
+			return cpus
+		}
+		if cpus, ok := readCgroupFile(mount, cgroupPath, "cpuset.cpus"); ok {
+			return cpus
+		}
+	}
+	if data, err := os.ReadFile("/sys/devices/system/cpu/online"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return "?"
+}
+
+// cgroupCPUKey identifies the cgroups that decide a process' cpu entitlement
+func cgroupCPUKey(p Process) string {
+	return p.Cgroups[""] + "|" + p.Cgroups["cpu"] + "|" + p.Cgroups["cpuset"]
+}
+
+// cpuQuotaAnnotation returns "[2.0 CPU on 0-3]" for processes that start
+// a new cgroup subtree, i.e. whose cgroup differs from their parent's
+func (t *Tree) cpuQuotaAnnotation(idx int) string {
+	process := t.procs[idx]
+	if process.Cgroups == nil {
+		return ""
+	}
+
+	key := cgroupCPUKey(process)
+	if parent := process.ParentIdx; parent != -1 && t.procs[parent].Print {
+		if cgroupCPUKey(t.procs[parent]) == key {
+			return ""
+		}
+	}
+
+	if annotation, ok := t.cpuQuotaCache[key]; ok {
+		return annotation
+	}
+
+	quota := "max"
+	if cpus := cgroupCPUs(process); cpus > 0 {
+		quota = strconv.FormatFloat(cpus, 'f', 1, 64)
+	}
+	annotation := fmt.Sprintf("[%s CPU on %s]", quota, cgroupCPUSet(process))
+	t.cpuQuotaCache[key] = annotation
+	return annotation
+}
+
+// cgroupMemory returns the memory charged to the cgroup of a process,
+// memory.current with cgroup v2, memory.usage_in_bytes with v1
+func cgroupMemory(p Process) (uint64, bool) {
+	var data []byte
+	var err error
+	if cgroupPath, ok := p.Cgroups[""]; ok {
+		data, err = os.ReadFile(filepath.Join(cgroupV2Mount(), cgroupPath, "memory.current"))
+	}
+	if cgroupPath, ok := p.Cgroups["memory"]; (data == nil || err != nil) && ok {
+		data, err = os.ReadFile(filepath.Join(cgroupMount, "memory", cgroupPath, "memory.usage_in_bytes"))
+	}
+	if data == nil || err != nil {
+		return 0, false
+	}
+	bytes, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return bytes, err == nil
+}
+
+// cgroupCPUPressure returns the share of the last 10 seconds some tasks
+// of the cgroup of a process waited for a cpu, from the cgroup v2
+// cpu.pressure "some avg10=1.50 avg60=..." line
+func cgroupCPUPressure(p Process) (float64, bool) {
+	cgroupPath, ok := p.Cgroups[""]
+	if !ok {
+		return 0, false
+	}
+	data, err := os.ReadFile(filepath.Join(cgroupV2Mount(), cgroupPath, "cpu.pressure"))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "some" {
+			continue
+		}
+		if avg10, found := strings.CutPrefix(fields[1], "avg10="); found {
+			pressure, err := strconv.ParseFloat(avg10, 64)
+			return pressure, err == nil
+		}
+	}
+	return 0, false
+}
+
+// cgroupStatsKey identifies the cgroups that account a process' resources
+func cgroupStatsKey(p Process) string {
+	return p.Cgroups[""] + "|" + p.Cgroups["memory"]
+}
+
+// cgroupStatsAnnotation returns "[mem 1.2G cpu.pressure 0.8%]" for
+// processes that start a new cgroup subtree, i.e. whose cgroup differs
+// from their parent's
+func (t *Tree) cgroupStatsAnnotation(idx int) string {
+	process := t.procs[idx]
+	if process.Cgroups == nil {
+		return ""
+	}
+
+	key := cgroupStatsKey(process)
+	if parent := process.ParentIdx; parent != -1 && t.procs[parent].Print {
+		if cgroupStatsKey(t.procs[parent]) == key {
+			return ""
+		}
+	}
+
+	if annotation, ok := t.cgroupStatsCache[key]; ok {
+		return annotation
+	}
+
+	var stats []string
+	if memory, ok := cgroupMemory(process); ok {
+		stats = append(stats, "mem "+humanSize(memory))
+	}
+	if pressure, ok := cgroupCPUPressure(process); ok {
+		stats = append(stats, fmt.Sprintf("cpu.pressure %.1f%%", pressure))
+	}
+	annotation := ""
+	if len(stats) > 0 {
+		annotation = "[" + strings.Join(stats, " ") + "]"
+	}
+	t.cgroupStatsCache[key] = annotation
+	return annotation
+}
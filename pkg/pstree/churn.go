@@ -0,0 +1,216 @@
+package pstree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// window of the rolling forks per second rate
+	churnWindow = 10 * time.Second
+)
+
+// fork events received by the netlink strategy
+var forkEvents atomic.Int64
+
+// procKey identifies a process across snapshots, despite PID reuse
+type procKey struct {
+	PID       int
+	StartTime uint64
+}
+
+// churnStats tracks processes appearing and disappearing between refreshes
+type churnStats struct {
+	seen    map[procKey]bool
+	since   time.Time
+	forks   int64
+	samples []churnSample
+}
+
+type churnSample struct {
+	at      time.Time
+	started int
+}
+
+// update compares the loaded processes with the previous refresh and
+// returns a one line summary of the churn
+func (c *churnStats) update(t *Tree) string {
+	now := time.Now()
+	current := make(map[procKey]bool, len(t.procs))
+	for _, p := range t.procs {
+		if p.Thread || p.Group {
+			continue
+		}
+		current[procKey{p.PID, p.StartTime}] = true
+	}
+
+	if c.seen == nil {
+		c.seen = current
+		c.since = now
+		c.forks = forkEvents.Load()
+		return "churn: waiting for the next refresh"
+	}
+
+	started, exited := 0, 0
+	for k := range current {
+		if !c.seen[k] {
+			started++
+		}
+	}
+	for k := range c.seen {
+		if !current[k] {
+			exited++
+		}
+	}
+	c.seen = current
+
+	// netlink sees every fork, even processes that lived between refreshes
+	forks := started
+	if t.config.WatchStrategy == "netlink" {
+		total := forkEvents.Load()
+		forks = int(total - c.forks)
+		c.forks = total
+	}
+
+	c.samples = append(c.samples, churnSample{now, forks})
+	for len(c.samples) > 0 && now.Sub(c.samples[0].at) > churnWindow {
+		c.samples = c.samples[1:]
+	}
+	total := 0
+	for _, sample := range c.samples {
+		total += sample.started
+	}
+	span := now.Sub(c.since)
+	if span > churnWindow {
+		span = churnWindow
+	}
+	rate := float64(total) / span.Seconds()
+
+	return fmt.Sprintf("churn: +%d started -%d exited since last refresh, %.1f forks/s over %s",
+		started, exited, rate, span.Round(100*time.Millisecond))
+}
+
+// churnNotifier returns a channel that is signalled when the process
+// table probably changed, according to the watch strategy. The poll
+// strategy never signals and relies on the refresh interval alone
+func churnNotifier(ctx context.Context, strategy string) (<-chan struct{}, error) {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	switch strategy {
+	case "poll":
+		return nil, nil
+
+	case "fast":
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("the fast watch strategy needs /proc")
+		}
+		go func() {
+			ticker := time.NewTicker(fastPollInterval)
+			defer ticker.Stop()
+			last := listProcPIDs()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if pids := listProcPIDs(); !slices.Equal(pids, last) {
+					last = pids
+					notify()
+				}
+			}
+		}()
+		return changes, nil
+
+	case "netlink":
+		fd, err := openProcConnector()
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			last := time.Time{}
+			err := readProcEvents(fd, func(ev ProcEvent) {
+				if ev.Kind == "fork" {
+					forkEvents.Add(1)
+				}
+				// coalesce event storms into one redraw per fastPollInterval
+				if time.Since(last) >= fastPollInterval {
+					last = time.Now()
+					notify()
+				}
+			})
+			log.Warnf("netlink watch stopped: %v", err)
+		}()
+		return changes, nil
+	}
+
+	return nil, fmt.Errorf("unknown watch strategy %q (want poll, fast or netlink)", strategy)
+}
+
+// listProcPIDs returns the names of the /proc/PID directories, which is
+// much cheaper than reading any of them
+func listProcPIDs() []string {
+	dir, err := os.Open("/proc")
+	if err != nil {
+		return nil
+	}
+	defer dir.Close()
+
+	names, _ := dir.Readdirnames(-1)
+	pids := names[:0]
+	for _, name := range names {
+		if _, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, name)
+		}
+	}
+	return pids
+}
+
+// loadProcessesLazy snapshots the process table reading only processes
+// that appeared since the last load, the others are taken from procCache.
+// The cpu usage of every process has to be measured again, so it takes a
+// full sampled load when it is shown or filtered on
+func (t *Tree) loadProcessesLazy() error {
+	if t.needsCPU() {
+		return t.loadProcessesSampled()
+	}
+	if resolveSourceName(t.config.Source) != "proc" {
+		return t.loadProcesses()
+	}
+
+	cache := make(map[string]Process, len(t.procCache))
+	t.procs = t.procs[:0]
+	for _, pid := range listProcPIDs() {
+		procDir := filepath.Join("/proc", pid)
+		proc, ok := t.procCache[procDir]
+		if !ok {
+			if proc, ok = t.readProcLinux(procDir); !ok {
+				continue
+			}
+		}
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+		cache[procDir] = proc
+		t.procs = append(t.procs, proc)
+	}
+	t.procCache = cache
+	t.prepareProcesses()
+	return nil
+}
@@ -0,0 +1,137 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/muesli/termenv"
+)
+
+// highCPUPercent is the cpu usage from which a process is colored as busy
+const highCPUPercent = 50
+
+var (
+	// the parts of the labels, on lines without a style of their own
+	pidStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	ownerStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+	commandStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+	// whole lines, by the state of the process
+	zombieStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	kernelStyle  = lipgloss.NewStyle().Faint(true)
+	highCPUStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+)
+
+// setColorMode applies --color: auto colors a terminal unless NO_COLOR is
+// set, always colors pipes too, never colors nothing
+func setColorMode(mode string) error {
+	switch mode {
+	case "auto":
+		// lipgloss checks the terminal and NO_COLOR itself
+	case "always":
+		profile := termenv.NewOutput(os.Stdout, termenv.WithTTY(true)).ColorProfile()
+		if profile == termenv.Ascii {
+			profile = termenv.ANSI256
+		}
+		lipgloss.SetColorProfile(profile)
+		log.SetColorProfile(profile)
+	case "never":
+		lipgloss.SetColorProfile(termenv.Ascii)
+		log.SetColorProfile(termenv.Ascii)
+	default:
+		return fmt.Errorf("invalid --color %q, expected auto, always or never", mode)
+	}
+	return nil
+}
+
+// stateStyle colors the line of a zombie, a kernel thread or a busy
+// process
+func stateStyle(process Process) (lipgloss.Style, bool) {
+	switch {
+	case process.State == "Z":
+		return zombieStyle, true
+	case process.CPUPercent >= highCPUPercent && !process.Thread:
+		return highCPUStyle, true
+	case process.Kernel:
+		return kernelStyle, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// colorLine styles a rendered line, whose label starts at labelStart:
+// as a whole per lineStyle, or else its pid, owner and command apart
+func (t *Tree) colorLine(idx int, line string, labelStart int) string {
+	process := t.procs[idx]
+	if _, ok := t.lineStyle(process); ok || lipgloss.ColorProfile() == termenv.Ascii {
+		return t.highlightLine(process, line)
+	}
+	if process.Group || labelStart >= len(line) {
+		return line
+	}
+
+	var text strings.Builder
+	text.WriteString(line[:labelStart])
+	label := line[labelStart:]
+	pid, owner := t.labelSpans(idx)
+	// the spans of a truncated label end with it
+	pos := 0
+	for _, part := range []struct {
+		span  [2]int
+		style lipgloss.Style
+	}{
+		{pid, pidStyle},
+		{owner, ownerStyle},
+	} {
+		start, end := max(runeStart(label, part.span[0]), pos), max(runeStart(label, part.span[1]), pos)
+		text.WriteString(label[pos:start])
+		text.WriteString(renderLines(part.style, label[start:end]))
+		pos = end
+	}
+	text.WriteString(renderLines(commandStyle, label[pos:]))
+	return text.String()
+}
+
+// labelSpans returns where the pid and the owner are in the label of a
+// process, empty when the label has none, as with the columns
+func (t *Tree) labelSpans(idx int) (pid, owner [2]int) {
+	process := t.procs[idx]
+	// a compacted label is only the name
+	if t.config.HeaderRow || len(t.activeColumns) > 0 || process.Compacted > 0 {
+		return
+	}
+	var birth string
+	if t.config.BirthOrder && process.BirthOrder > 0 {
+		birth = fmt.Sprintf("#%d ", process.BirthOrder)
+	}
+	pid[1] = len(fmt.Sprintf("%05d%s", process.PID, t.nsPIDSuffix(process)))
+	start := pid[1] + 1 + len(birth)
+	owner = [2]int{start, start + len(process.Owner)}
+	return
+}
+
+// runeStart moves i back to the start of the rune it falls in, the
+// truncation of a label may have put a "…" where a span ends
+func runeStart(s string, i int) int {
+	i = min(i, len(s))
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// renderLines styles text line by line, lipgloss would pad the lines of
+// a multi-line text to the same width
+func renderLines(style lipgloss.Style, text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = style.Render(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,199 @@
+package pstree
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// column is a field selectable with -o
+type column struct {
+	header string
+	// display width of the --header-row cell, negative to align left
+	width int
+	value func(Process) string
+}
+
+// columnTable holds the columns -o accepts
+var columnTable = map[string]column{
+	"pid":  {"PID", 6, func(p Process) string { return strconv.Itoa(p.PID) }},
+	"ppid": {"PPID", 6, func(p Process) string { return strconv.Itoa(p.PPID) }},
+	"pgid": {"PGID", 6, func(p Process) string { return strconv.Itoa(p.PGID) }},
+	"sid":  {"SID", 6, func(p Process) string { return strconv.Itoa(p.SID) }},
+	"user": {"OWNER", -8, func(p Process) string { return p.Owner }},
+	"uid":  {"UID", 5, func(p Process) string { return strconv.Itoa(p.UID) }},
+	"threads": {"THREADS", 7, func(p Process) string {
+		return strconv.Itoa(p.ThreadCount)
+	}},
+	"state": {"S", -1, func(p Process) string { return orDash(p.State) }},
+	"tty":   {"TTY", -7, ttyName},
+	"cpu":   {"%CPU", 5, func(p Process) string { return fmt.Sprintf("%.1f", p.CPUPercent) }},
+	"rss":   {"RSS", 6, func(p Process) string { return sizeOrDash(p.RSS) }},
+	"vsz":   {"VSZ", 6, func(p Process) string { return sizeOrDash(p.VSZ) }},
+	"stime": {"STIME", 5, func(p Process) string {
+		if p.Started.IsZero() {
+			return "-"
+		}
+		return startTime(p.Started, time.Now())
+	}},
+	"etime": {"ELAPSED", 7, func(p Process) string {
+		if p.Started.IsZero() {
+			return "-"
+		}
+		return formatElapsed(time.Since(p.Started))
+	}},
+	"ni":     {"NI", 3, func(p Process) string { return strconv.Itoa(p.Nice) }},
+	"pri":    {"PRI", 4, func(p Process) string { return strconv.Itoa(p.Priority) }},
+	"rtprio": {"RTPRIO", 6, func(p Process) string { return strconv.Itoa(p.RTPriority) }},
+	"policy": {"POLICY", -8, func(p Process) string { return orDash(p.Policy) }},
+	"oom":    {"OOM", 5, func(p Process) string { return strconv.Itoa(p.OOMScore) }},
+	"oomadj": {"OOMADJ", 6, func(p Process) string { return strconv.Itoa(p.OOMScoreAdj) }},
+	"fds":    {"FDS", 5, fdCount},
+	"read":   {"READ", 6, func(p Process) string { return ioBytes(p, p.ReadBytes) }},
+	"write":  {"WRITE", 6, func(p Process) string { return ioBytes(p, p.WriteBytes) }},
+	"ports":  {"PORTS", -12, func(p Process) string { return orDash(strings.Join(p.Ports, ",")) }},
+	"caps": {"CAPS", -20, func(p Process) string {
+		if p.CapEff == 0 {
+			return "-"
+		}
+		return capsSummary(p.CapEff)
+	}},
+	"cwd":  {"CWD", -20, func(p Process) string { return orDash(p.Cwd) }},
+	"comm": {"COMM", -15, func(p Process) string { return orDash(p.Comm) }},
+	"cmd":  {"COMMAND", 0, func(p Process) string { return p.Cmd }},
+}
+
+// columnValue formats a column of a process, with the pid namespace
+// suffix, and a dash for the cpu usage until it is sampled
+func (t *Tree) columnValue(name string, process Process) string {
+	switch {
+	case name == "pid":
+		return columnTable[name].value(process) + t.nsPIDSuffix(process)
+	case name == "cpu" && !t.cpuSampled:
+		return "-"
+	}
+	return columnTable[name].value(process)
+}
+
+// headerColumns are the columns of --header-row without -o. The cpu
+// usage is left out, measuring it takes --sample
+var headerColumns = []string{"pid", "user", "threads", "rss"}
+
+// setupColumns validates -o, and picks the columns of --header-row
+func (t *Tree) setupColumns() error {
+	t.activeColumns = nil
+	for _, name := range t.config.Format {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columnTable[name]; !ok {
+			names := make([]string, 0, len(columnTable))
+			for known := range columnTable {
+				names = append(names, known)
+			}
+			slices.Sort(names)
+			return fmt.Errorf("unknown column %q, expected some of %s", name, strings.Join(names, ","))
+		}
+		t.activeColumns = append(t.activeColumns, name)
+	}
+	if len(t.activeColumns) == 0 && t.config.HeaderRow {
+		t.activeColumns = slices.Clone(headerColumns)
+		if t.config.ShowTTY {
+			t.activeColumns = append(t.activeColumns, "tty")
+		}
+	}
+	return nil
+}
+
+// columnShown reports whether a column is printed
+func (t *Tree) columnShown(name string) bool {
+	return slices.Contains(t.activeColumns, name)
+}
+
+// cellColumns are the columns printed left of the tree with
+// --header-row, the command is the tree itself
+func (t *Tree) cellColumns() []string {
+	return slices.DeleteFunc(slices.Clone(t.activeColumns), func(name string) bool { return name == "cmd" })
+}
+
+// columnHeader names the columns and the tree that follows them
+func (t *Tree) columnHeader() string {
+	var header string
+	for _, name := range t.cellColumns() {
+		col := columnTable[name]
+		header += columnCell(col.width, col.header) + " "
+	}
+	return header + "COMMAND"
+}
+
+// columnCells formats the columns of a process, "-" marks unknown values
+func (t *Tree) columnCells(idx int) string {
+	process := t.procs[idx]
+	var cells string
+	for _, name := range t.cellColumns() {
+		col := columnTable[name]
+		cells += columnCell(col.width, t.columnValue(name, process)) + " "
+	}
+	return cells
+}
+
+// columnCell fits a value to the display width of its cell, cut with a
+// "+" like ps does with long user names, and aligned left when width is
+// negative
+func columnCell(width int, value string) string {
+	if width < 0 {
+		return runewidth.FillRight(runewidth.Truncate(value, -width, "+"), -width)
+	}
+	return runewidth.FillLeft(runewidth.Truncate(value, width, "+"), width)
+}
+
+// columnLabel joins the -o columns of a process into its node label
+func (t *Tree) columnLabel(process Process, birth string) string {
+	values := make([]string, 0, len(t.activeColumns))
+	for _, name := range t.activeColumns {
+		value := t.columnValue(name, process)
+		if name == "cmd" {
+			value = birth + value
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, " ")
+}
+
+// startTime formats a start time like ps: the time of day when today,
+// the day otherwise, and the year before this one
+func startTime(started, now time.Time) string {
+	switch {
+	case started.YearDay() == now.YearDay() && started.Year() == now.Year():
+		return started.Format("15:04")
+	case started.Year() == now.Year():
+		return started.Format("Jan02")
+	}
+	return started.Format("2006")
+}
+
+// orDash returns s, or "-" when it's empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// ioBytes formats an io counter, "?" when it can't be read
+func ioBytes(process Process, bytes uint64) string {
+	if !process.IOKnown {
+		return "?"
+	}
+	return humanSize(bytes)
+}
+
+// sizeOrDash formats a byte count, "-" when unknown
+func sizeOrDash(bytes uint64) string {
+	if bytes == 0 {
+		return "-"
+	}
+	return humanSize(bytes)
+}
@@ -0,0 +1,49 @@
+package pstree
+
+import "fmt"
+
+// compactSiblings folds the identical leaves below each printed process
+// into the first of them, printed as N*[name] like the classic pstree.
+// Identical means the same label but for the pid, and the same columns
+// and highlighting, though only the command name is printed. The folded
+// processes stay marked to print, they are only unlinked from the tree
+func (t *Tree) compactSiblings() {
+	for i := range t.procs {
+		if !t.procs[i].Print {
+			continue
+		}
+		first := map[string]int{}
+		prev := -1
+		for child := t.procs[i].ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+			process := t.procs[child]
+			if process.ChildIdx != -1 || process.Group {
+				prev = child
+				continue
+			}
+			key := t.compactKey(child)
+			kept, ok := first[key]
+			if !ok {
+				first[key] = child
+				prev = child
+				continue
+			}
+			t.procs[kept].Compacted++
+			if prev == -1 {
+				t.procs[i].ChildIdx = process.SisterIdx
+			} else {
+				t.procs[prev].SisterIdx = process.SisterIdx
+			}
+		}
+	}
+}
+
+// compactKey is what two siblings must share to be folded together
+func (t *Tree) compactKey(idx int) string {
+	process := t.procs[idx]
+	key := t.processLabel(idx, false)
+	if t.config.HeaderRow {
+		key = t.columnCells(idx) + "\x00" + key
+	}
+	leader := process.PID == process.PGID
+	return fmt.Sprintf("%s\x00%d\x00%t", key, process.Highlight, leader)
+}
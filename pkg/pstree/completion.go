@@ -0,0 +1,73 @@
+package pstree
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completePids completes the live pids, described by their command
+// name, for the pid arguments and flags
+func completePids(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	list, err := Snapshot()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	slices.SortFunc(list, func(a, b Process) int { return a.PID - b.PID })
+	var completions []cobra.Completion
+	for _, process := range list {
+		pid := strconv.Itoa(process.PID)
+		if !strings.HasPrefix(pid, toComplete) {
+			continue
+		}
+		completions = append(completions, cobra.CompletionWithDesc(pid, processName(process)))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// processName is the command name of a process, or the first word of its
+// command line when the source has no name
+func processName(process Process) string {
+	if process.Comm != "" {
+		return process.Comm
+	}
+	name, _, _ := strings.Cut(process.Cmd, " ")
+	return name
+}
+
+// completeUsers completes the users of the passwd file, and the owners of
+// the live processes on systems where users live elsewhere
+func completeUsers(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var names []string
+	if file, err := os.Open("/etc/passwd"); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" || line[0] == '#' || line[0] == '+' || line[0] == '-' {
+				continue
+			}
+			name, _, _ := strings.Cut(line, ":")
+			names = append(names, name)
+		}
+		file.Close()
+	}
+	if list, err := Snapshot(); err == nil {
+		for _, process := range list {
+			names = append(names, process.Owner)
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	var completions []cobra.Completion
+	for _, name := range names {
+		if name != "" && strings.HasPrefix(name, toComplete) {
+			completions = append(completions, name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
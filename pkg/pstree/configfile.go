@@ -0,0 +1,289 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts the environment variables setting flag defaults, e.g.
+// PSTREE_GRAPHICS=3 or PSTREE_HIDE_KERNEL=true
+const envPrefix = "PSTREE_"
+
+// optsEnv holds flags read as if they came before the command line's,
+// like LESS, e.g. PSTREE_OPTS="-p -g 3"
+const optsEnv = envPrefix + "OPTS"
+
+// styleColors are the colors the colors section of the config file
+// changes, by name
+var styleColors = map[string]*lipgloss.Style{
+	"highlight":   &highlightMatchStyle,
+	"ancestor":    &highlightAncestorStyle,
+	"realtime":    &realTimeStyle,
+	"oom-victim":  &oomVictimStyle,
+	"oom-risk":    &oomRiskStyle,
+	"deleted-exe": &deletedExeStyle,
+	"setuid":      &setuidStyle,
+	"pid":         &pidStyle,
+	"owner":       &ownerStyle,
+	"command":     &commandStyle,
+	"zombie":      &zombieStyle,
+	"kernel":      &kernelStyle,
+	"high-cpu":    &highCPUStyle,
+}
+
+// defaultConfigPath is where the config file is read from without
+// --config, e.g. ~/.config/pstree/config.yaml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pstree", "config.yaml")
+}
+
+// readConfigFile reads the settings of a config file, long flag names
+// and their values, plus the colors and custom-graphics sections. A
+// missing file is only an error when it was asked for with --config
+func readConfigFile(path string, explicit bool) (map[string]any, error) {
+	settings := map[string]any{}
+	if path == "" {
+		return settings, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// applyConfigFile sets the flags of cmd left off the command line from
+// PSTREE_OPTS, then the environment, then the config file, so flags win
+// over PSTREE_OPTS, then the environment, the file and the defaults
+func applyConfigFile(cmd *cobra.Command, path string) error {
+	if err := applyOptsEnv(cmd.Flags()); err != nil {
+		return err
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	settings, err := readConfigFile(path, explicit)
+	if err != nil {
+		return err
+	}
+
+	// a key may be meant for another subcommand, but not for none
+	known := map[string]bool{"colors": true, "custom-graphics": true}
+	commands := []*cobra.Command{cmd.Root()}
+	for len(commands) > 0 {
+		c := commands[0]
+		commands = append(commands[1:], c.Commands()...)
+		c.Flags().VisitAll(func(flag *pflag.Flag) { known[flag.Name] = true })
+		c.PersistentFlags().VisitAll(func(flag *pflag.Flag) { known[flag.Name] = true })
+	}
+	for key := range settings {
+		if !known[key] {
+			return fmt.Errorf("%s: unknown flag %q", path, key)
+		}
+	}
+
+	var errs []error
+	flags := cmd.Flags()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || slices.Contains([]string{"config", "help", "version"}, flag.Name) {
+			return
+		}
+		env := envPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		if value, ok := os.LookupEnv(env); ok {
+			if err := flags.Set(flag.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", env, err))
+			}
+			return
+		}
+		if value, ok := settings[flag.Name]; ok {
+			if err := setFlagValue(flags, flag.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", path, flag.Name, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if err := applyCustomGraphics(path, settings["custom-graphics"]); err != nil {
+		return err
+	}
+	return applyColors(path, settings["colors"])
+}
+
+// optRecorder is the value of a flag of the PSTREE_OPTS parse, it keeps
+// what the flag was given for the real one
+type optRecorder struct {
+	kind   string
+	values []string
+}
+
+func (r *optRecorder) String() string { return "" }
+
+func (r *optRecorder) Set(value string) error {
+	r.values = append(r.values, value)
+	return nil
+}
+
+func (r *optRecorder) Type() string { return r.kind }
+
+// applyOptsEnv sets the flags left off the command line to the values
+// PSTREE_OPTS gives them. The flags cmd doesn't have are skipped, they
+// may be meant for another subcommand
+func applyOptsEnv(flags *pflag.FlagSet) error {
+	opts, ok := os.LookupEnv(optsEnv)
+	if !ok {
+		return nil
+	}
+	args, err := splitOpts(opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", optsEnv, err)
+	}
+
+	// parse with the same names and shorthands into recorders, so the
+	// command line values aren't touched
+	shadow := pflag.NewFlagSet(optsEnv, pflag.ContinueOnError)
+	shadow.ParseErrorsWhitelist.UnknownFlags = true
+	shadow.SetOutput(io.Discard)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if slices.Contains([]string{"config", "help", "version"}, flag.Name) {
+			return
+		}
+		shadow.AddFlag(&pflag.Flag{
+			Name:        flag.Name,
+			Shorthand:   flag.Shorthand,
+			NoOptDefVal: flag.NoOptDefVal,
+			Value:       &optRecorder{kind: flag.Value.Type()},
+		})
+	})
+	if err := shadow.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", optsEnv, err)
+	}
+	if shadow.NArg() > 0 {
+		log.Warnf("%s: ignoring %s", optsEnv, strings.Join(shadow.Args(), " "))
+	}
+
+	var errs []error
+	shadow.Visit(func(opt *pflag.Flag) {
+		if flags.Changed(opt.Name) {
+			return
+		}
+		for _, value := range opt.Value.(*optRecorder).values {
+			if err := flags.Set(opt.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", optsEnv, opt.Name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// splitOpts splits PSTREE_OPTS into args at blanks, like a shell would,
+// quotes and backslashes keep them in an arg
+func splitOpts(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// setFlagValue sets a flag to a scalar of the config file, or to each
+// element of a list, which the slice flags append
+func setFlagValue(flags *pflag.FlagSet, name string, value any) error {
+	switch value := value.(type) {
+	case []any:
+		for _, element := range value {
+			if err := flags.Set(name, fmt.Sprint(element)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any, nil:
+		return fmt.Errorf("expected a value or a list")
+	}
+	return flags.Set(name, fmt.Sprint(value))
+}
+
+// applyColors changes the foreground of the styles named in the colors
+// section, to ANSI color numbers or #rrggbb values
+func applyColors(path string, section any) error {
+	if section == nil {
+		return nil
+	}
+	colors, ok := section.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: colors: expected names and colors", path)
+	}
+	for name, color := range colors {
+		style, ok := styleColors[name]
+		if !ok {
+			names := make([]string, 0, len(styleColors))
+			for known := range styleColors {
+				names = append(names, known)
+			}
+			slices.Sort(names)
+			return fmt.Errorf("%s: colors: unknown color %q, expected some of %s", path, name, strings.Join(names, ", "))
+		}
+		*style = style.Foreground(lipgloss.Color(fmt.Sprint(color)))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package pstree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// length of a container id and of its short form
+	containerIDLen      = 64
+	shortContainerIDLen = 12
+
+	// how long a container runtime gets to answer a name lookup
+	containerLookupTimeout = time.Second
+)
+
+// containerPrefixes are the scope prefixes of the container runtimes,
+// e.g. docker-ID.scope or crio-ID.scope under systemd
+var containerPrefixes = []string{"docker-", "libpod-", "crio-", "cri-containerd-", "containerd-"}
+
+// containerID finds the id of the container a process runs in from its
+// cgroup paths: /docker/ID, /system.slice/docker-ID.scope,
+// /machine.slice/libpod-ID.scope, /kubepods/.../crio-ID.scope, ...
+// It returns "" for processes outside containers
+func containerID(cgroups map[string]string) string {
+	for _, cgroupPath := range cgroups {
+		for dir := cgroupPath; dir != "/" && dir != "." && dir != ""; dir = path.Dir(dir) {
+			name := strings.TrimSuffix(path.Base(dir), ".scope")
+			for _, prefix := range containerPrefixes {
+				name = strings.TrimPrefix(name, prefix)
+			}
+			if isContainerID(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// isContainerID reports whether s is a 64 digit hex id
+func isContainerID(s string) bool {
+	if len(s) != containerIDLen {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// containerName returns what pstree shows for a container: its name
+// in its pod with --group-pods, its name with --resolve-containers when
+// a runtime knows it, its short id if not
+func (t *Tree) containerName(id string) string {
+	if t.config.GroupPods {
+		t.loadKubeNames()
+		if name, ok := t.kubeContainers[id]; ok {
+			return name
+		}
+	}
+	if t.config.ResolveContainers {
+		name, ok := t.containerNames[id]
+		if !ok {
+			name = lookupContainerName(id)
+			t.containerNames[id] = name
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return id[:shortContainerIDLen]
+}
+
+// containerSockets lists the Docker API sockets to ask for container
+// names, podman serves a compatible API
+func containerSockets() []string {
+	sockets := []string{"/var/run/docker.sock", "/run/podman/podman.sock"}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets,
+			filepath.Join(runtimeDir, "docker.sock"),
+			filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	return sockets
+}
+
+// lookupContainerName asks the container runtimes for the name of a
+// container with GET /containers/ID/json
+func lookupContainerName(id string) string {
+	for _, socket := range containerSockets() {
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		name, err := inspectContainer(socket, id)
+		if err != nil {
+			log.Debugf("lookupContainerName: %s: %v", socket, err)
+			continue
+		}
+		return name
+	}
+	return ""
+}
+
+// inspectContainer queries one Docker API socket for a container name
+func inspectContainer(socket, id string) (string, error) {
+	client := &http.Client{
+		Timeout: containerLookupTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://localhost/containers/" + id + "/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("container %s: %s", id[:shortContainerIDLen], resp.Status)
+	}
+
+	var container struct {
+		Name string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(container.Name, "/"), nil
+}
+
+// containerAnnotation returns "[ID]" or "[NAME]" for processes that start a
+// container, i.e. whose container differs from their parent's
+func (t *Tree) containerAnnotation(idx int) string {
+	process := t.procs[idx]
+	if process.Container == "" {
+		return ""
+	}
+	if parent := process.ParentIdx; parent != -1 && t.procs[parent].Container == process.Container {
+		return ""
+	}
+	return "[" + t.containerName(process.Container) + "]"
+}
+
+// groupByContainer inserts a "container ID" node above the processes
+// of each container
+func (t *Tree) groupByContainer() {
+	containers := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if !p.Thread {
+			containers[p.PID] = p.Container
+		}
+	}
+	t.insertGroups(containers, func(id string) string { return "container " + t.containerName(id) })
+}
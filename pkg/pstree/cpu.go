@@ -0,0 +1,72 @@
+package pstree
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// USER_HZ, the unit of the cpu times in /proc/PID/stat
+	clockTicks = 100
+)
+
+// loadProcessesSampled loads the processes, measuring their cpu usage
+// when it is shown or a filter needs it
+func (t *Tree) loadProcessesSampled() error {
+	if t.needsCPU() {
+		return t.sampleCPU(t.config.Sample)
+	}
+	return t.loadProcesses()
+}
+
+// needsCPU reports whether the cpu usage is shown or a filter needs it
+func (t *Tree) needsCPU() bool {
+	return t.config.ShowCPU || t.config.MinCPU > 0 || t.whereCPU || t.columnShown("cpu")
+}
+
+// sampleCPU snapshots the process table twice, interval apart, and
+// fills CPUPercent of the processes of the second snapshot
+func (t *Tree) sampleCPU(interval time.Duration) error {
+	if err := t.loadProcesses(); err != nil {
+		return err
+	}
+	before := make(map[procKey]uint64, len(t.procs))
+	for _, p := range t.procs {
+		before[procKey{p.PID, p.StartTime}] = p.CPUTicks
+	}
+
+	start := time.Now()
+	time.Sleep(interval)
+
+	if err := t.loadProcesses(); err != nil {
+		return err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	for i := range t.procs {
+		p := &t.procs[i]
+		// processes started during the interval count from zero
+		ticks := p.CPUTicks - min(before[procKey{p.PID, p.StartTime}], p.CPUTicks)
+		p.CPUPercent = float64(ticks) / clockTicks / elapsed * 100
+	}
+	t.cpuSampled = true
+	return nil
+}
+
+// cpuAnnotation returns the cpu usage of a process, and of its subtree
+// with --cumulative, e.g. "(cpu 1.5% tree 42.0%)". The cpu column of -o
+// already holds the former
+func (t *Tree) cpuAnnotation(process Process) string {
+	var parts []string
+	if !t.columnShown("cpu") {
+		parts = append(parts, fmt.Sprintf("cpu %.1f%%", process.CPUPercent))
+	}
+	if t.config.Cumulative {
+		parts = append(parts, fmt.Sprintf("tree %.1f%%", process.SubtreeCPU))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
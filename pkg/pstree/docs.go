@@ -0,0 +1,84 @@
+package pstree
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"github.com/spf13/pflag"
+)
+
+// hostDefaults describe the flag defaults that depend on the machine
+// running pstree, the docs show them instead of the values found there
+var hostDefaults = map[string]struct{ zero, text string }{
+	"user":     {"[]", "the current user"},
+	"graphics": {"", "utf8 with a UTF-8 locale, ascii otherwise"},
+}
+
+// portableDefaults replaces the host dependent defaults of the flags of
+// every command with their description, so the generated docs don't
+// depend on who built them. The docs carry no generation date either
+func portableDefaults(root *cobra.Command) {
+	root.DisableAutoGenTag = true
+	fix := func(flag *pflag.Flag) {
+		if host, ok := hostDefaults[flag.Name]; ok {
+			flag.DefValue = host.zero
+			flag.Usage += " (default " + host.text + ")"
+		}
+		if path := defaultConfigPath(); path != "" {
+			flag.Usage = strings.ReplaceAll(flag.Usage, path, "~/.config/pstree/config.yaml")
+		}
+	}
+	commands := []*cobra.Command{root}
+	for len(commands) > 0 {
+		c := commands[0]
+		commands = append(commands[1:], c.Commands()...)
+		c.Flags().VisitAll(fix)
+		c.PersistentFlags().VisitAll(fix)
+	}
+}
+
+func newGenManCmd() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "gen-man [--dir DIR]",
+		Short: "Write the man pages of pstree and its subcommands",
+		Long: `gen-man writes pstree.1 and a page per subcommand, e.g. pstree-watch.1,
+from the flags of the binary, for packages to install. SOURCE_DATE_EPOCH
+sets the date of the pages.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			root := cmd.Root()
+			portableDefaults(root)
+			header := &doc.GenManHeader{Title: "PSTREE", Section: "1", Source: "pstree " + version}
+			return doc.GenManTree(root, header, dir)
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory the pages are written to")
+	return cmd
+}
+
+func newGenDocsCmd() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "gen-docs [--dir DIR]",
+		Short: "Write the markdown reference of pstree and its subcommands",
+		Long: `gen-docs writes pstree.md and a page per subcommand, e.g. pstree_watch.md,
+from the flags of the binary.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			root := cmd.Root()
+			portableDefaults(root)
+			return doc.GenMarkdownTree(root, dir)
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory the pages are written to")
+	return cmd
+}
@@ -0,0 +1,33 @@
+package pstree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// envAnnotation returns the --env variables set for a process, e.g.
+// "RAILS_ENV=production". The environment is only read for the printed
+// processes, from /proc/PID/environ of the Linux /proc source
+func (t *Tree) envAnnotation(process Process) string {
+	if resolveSourceName(t.config.Source) != "proc" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(process.PID), "environ"))
+	if err != nil {
+		return ""
+	}
+
+	var vars []string
+	for _, name := range t.config.Env {
+		for _, entry := range bytes.Split(data, []byte{0}) {
+			if value, found := bytes.CutPrefix(entry, []byte(name+"=")); found {
+				vars = append(vars, name+"="+string(value))
+				break
+			}
+		}
+	}
+	return strings.Join(vars, " ")
+}
@@ -0,0 +1,143 @@
+package pstree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ProcEvent is a single process lifecycle event
+type ProcEvent struct {
+	Kind string // fork, exec or exit
+	Time time.Time
+	PID  int
+	// parent of a forked process
+	PPID int
+	// exit status in wait(2) encoding, for exit events
+	ExitCode int
+}
+
+func (t *Tree) newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Print process fork, exec and exit events as they happen",
+		Long: `events subscribes to the kernel process connector (Linux only, needs root
+or CAP_NET_ADMIN) and prints fork, exec and exit events, each annotated
+with the current ancestry of the process.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if t.config.Graphics < 0 || t.config.Graphics >= len(treeChars) {
+				t.config.Graphics = GraphicsASCII
+			}
+
+			if err := t.loadProcesses(); err != nil {
+				return err
+			}
+			lineage := newLineage()
+			for _, p := range t.procs {
+				lineage.add(p.PID, p.PPID, commandName(p.Cmd))
+			}
+
+			return streamEvents(func(ev ProcEvent) {
+				t.printEvent(os.Stdout, lineage, ev)
+			})
+		},
+	}
+
+	t.addGraphicsFlag(cmd)
+
+	return cmd
+}
+
+// lineage remembers parents and names of processes seen so far, so the
+// ancestry of a process can still be printed after its parents exited
+type lineage struct {
+	parent map[int]int
+	name   map[int]string
+}
+
+func newLineage() *lineage {
+	return &lineage{parent: map[int]int{}, name: map[int]string{}}
+}
+
+func (l *lineage) add(pid, ppid int, name string) {
+	l.parent[pid] = ppid
+	l.name[pid] = name
+}
+
+func (l *lineage) remove(pid int) {
+	delete(l.parent, pid)
+	delete(l.name, pid)
+}
+
+// path returns the names from the top ancestor down to pid
+func (l *lineage) path(pid int) []string {
+	var names []string
+	seen := map[int]bool{}
+	for pid > 0 && !seen[pid] {
+		seen[pid] = true
+		name, ok := l.name[pid]
+		if !ok {
+			break
+		}
+		names = append([]string{name}, names...)
+		pid = l.parent[pid]
+	}
+	return names
+}
+
+// commandName returns the short name of a command line
+func commandName(cmd string) string {
+	if fields := strings.Fields(cmd); len(fields) > 0 {
+		return stripPath(fields[0])
+	}
+	return cmd
+}
+
+// liveName returns the current name of a process, from /proc when possible
+func liveName(pid int) string {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil && len(data) > 0 {
+		return commandName(strings.ReplaceAll(string(data), "\x00", " "))
+	}
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return "?"
+}
+
+func (t *Tree) printEvent(w io.Writer, l *lineage, ev ProcEvent) {
+	arrow := " -> "
+	if unicodeGraphics(t.config.Graphics) {
+		arrow = " → "
+	}
+
+	var detail string
+	switch ev.Kind {
+	case "fork":
+		l.add(ev.PID, ev.PPID, l.name[ev.PPID])
+		detail = fmt.Sprintf("%d forked %d", ev.PPID, ev.PID)
+	case "exec":
+		ppid, ok := l.parent[ev.PID]
+		if !ok {
+			ppid = -1
+		}
+		l.add(ev.PID, ppid, liveName(ev.PID))
+		detail = fmt.Sprintf("%d %s", ev.PID, l.name[ev.PID])
+	case "exit":
+		status := fmt.Sprintf("status %d", (ev.ExitCode>>8)&0xff)
+		if signal := ev.ExitCode & 0x7f; signal != 0 {
+			status = fmt.Sprintf("signal %d", signal)
+		}
+		detail = fmt.Sprintf("%d %s", ev.PID, status)
+	}
+
+	fmt.Fprintf(w, "%s %-5s %-28s %s\n", ev.Time.Format("15:04:05.000"), ev.Kind, detail, strings.Join(l.path(ev.PID), arrow))
+
+	if ev.Kind == "exit" {
+		l.remove(ev.PID)
+	}
+}
@@ -0,0 +1,132 @@
+package pstree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernel process connector constants, see linux/connector.h and linux/cn_proc.h
+const (
+	cnIdxProc          = 1
+	cnValProc          = 1
+	procCnMcastListen  = 1
+	procEventFork      = 0x00000001
+	procEventExec      = 0x00000002
+	procEventExit      = 0x80000000
+	cnMsgSize          = 20 // struct cn_msg without payload
+	procEventHeaderLen = 16 // what, cpu, timestamp_ns
+)
+
+// streamEvents subscribes to the proc connector and calls handle for
+// every fork, exec and exit of a process (threads are skipped)
+func streamEvents(handle func(ProcEvent)) error {
+	fd, err := openProcConnector()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	return readProcEvents(fd, handle)
+}
+
+// openProcConnector returns a netlink socket subscribed to process events
+func openProcConnector() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return -1, fmt.Errorf("proc connector: %w", err)
+	}
+
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc, Pid: uint32(os.Getpid())}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("proc connector: %w", err)
+	}
+
+	// nlmsghdr + cn_msg + PROC_CN_MCAST_LISTEN
+	msg := make([]byte, unix.NLMSG_HDRLEN+cnMsgSize+4)
+	binary.NativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:], unix.NLMSG_DONE)
+	binary.NativeEndian.PutUint32(msg[12:], uint32(os.Getpid()))
+	cn := msg[unix.NLMSG_HDRLEN:]
+	binary.NativeEndian.PutUint32(cn[0:], cnIdxProc)
+	binary.NativeEndian.PutUint32(cn[4:], cnValProc)
+	binary.NativeEndian.PutUint16(cn[16:], 4)
+	binary.NativeEndian.PutUint32(cn[cnMsgSize:], procCnMcastListen)
+
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("proc connector subscribe: %w", err)
+	}
+	return fd, nil
+}
+
+// readProcEvents decodes the events received on a proc connector socket
+func readProcEvents(fd int, handle func(ProcEvent)) error {
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return err
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if ev, ok := parseProcEvent(m.Data); ok {
+				handle(ev)
+			}
+		}
+	}
+}
+
+// parseProcEvent decodes the struct proc_event carried in a cn_msg
+func parseProcEvent(data []byte) (ProcEvent, bool) {
+	if len(data) < cnMsgSize+procEventHeaderLen {
+		return ProcEvent{}, false
+	}
+	ev := data[cnMsgSize:]
+	what := binary.NativeEndian.Uint32(ev[0:])
+	body := ev[procEventHeaderLen:]
+	u32 := func(i int) int {
+		if len(body) < (i+1)*4 {
+			return 0
+		}
+		return int(int32(binary.NativeEndian.Uint32(body[i*4:])))
+	}
+
+	event := ProcEvent{Time: time.Now()}
+	switch what {
+	case procEventFork:
+		// parent_pid, parent_tgid, child_pid, child_tgid
+		if u32(2) != u32(3) {
+			return event, false
+		}
+		event.Kind = "fork"
+		event.PPID = u32(1)
+		event.PID = u32(3)
+	case procEventExec:
+		// process_pid, process_tgid
+		event.Kind = "exec"
+		event.PID = u32(1)
+	case procEventExit:
+		// process_pid, process_tgid, exit_code, exit_signal
+		if u32(0) != u32(1) {
+			return event, false
+		}
+		event.Kind = "exit"
+		event.PID = u32(1)
+		event.ExitCode = u32(2)
+	default:
+		return event, false
+	}
+	return event, true
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package pstree
+
+import "fmt"
+
+func streamEvents(handle func(ProcEvent)) error {
+	return fmt.Errorf("process events are only available on Linux")
+}
+
+func openProcConnector() (int, error) {
+	return -1, fmt.Errorf("process events are only available on Linux")
+}
+
+func readProcEvents(fd int, handle func(ProcEvent)) error {
+	return fmt.Errorf("process events are only available on Linux")
+}
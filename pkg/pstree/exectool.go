@@ -0,0 +1,113 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// diagnosticTools are the command lines of the exec --tool names, {} is
+// the pid
+var diagnosticTools = map[string][]string{
+	"strace": {"strace", "-f", "-p", "{}"},
+	"gdb":    {"gdb", "-p", "{}"},
+	"lsof":   {"lsof", "-p", "{}"},
+	"py-spy": {"py-spy", "dump", "--pid", "{}"},
+}
+
+func (t *Tree) newExecCmd() *cobra.Command {
+	var (
+		tool     string
+		terminal bool
+	)
+
+	names := make([]string, 0, len(diagnosticTools))
+	for name := range diagnosticTools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	cmd := &cobra.Command{
+		Use:   "exec pid --tool " + strings.Join(names, "|") + " [--terminal] [-- tool args]",
+		Short: "Run a diagnostic tool against a process",
+		Long: `exec attaches a diagnostic tool to a process, e.g. strace -f -p PID,
+in the terminal pstree runs in, or in a new terminal window with
+--terminal, $TERMINAL or x-terminal-emulator. The arguments after --
+are passed on to the tool.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if dash := cmd.ArgsLenAtDash(); dash != -1 {
+				args = args[:dash]
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var extra []string
+			if dash := cmd.ArgsLenAtDash(); dash != -1 {
+				args, extra = args[:dash], args[dash:]
+			}
+			pid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return &usageError{fmt.Errorf("invalid pid %q", args[0])}
+			}
+			template, ok := diagnosticTools[tool]
+			if !ok {
+				return &usageError{fmt.Errorf("unknown tool %q, expected one of %s", tool, strings.Join(names, ", "))}
+			}
+			if !processAlive(pid) {
+				return fmt.Errorf("no such process: %d", pid)
+			}
+
+			argv := make([]string, 0, len(template)+len(extra))
+			for _, arg := range template {
+				argv = append(argv, strings.ReplaceAll(arg, "{}", strconv.Itoa(pid)))
+			}
+			argv = append(argv, extra...)
+			if _, err := exec.LookPath(argv[0]); err != nil {
+				return fmt.Errorf("%s is not installed: %w", argv[0], err)
+			}
+
+			if terminal {
+				return openTerminal(argv)
+			}
+			run := exec.Command(argv[0], argv[1:]...)
+			run.Stdin, run.Stdout, run.Stderr = os.Stdin, os.Stdout, os.Stderr
+			err = run.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return &exitStatusError{Code: exitErr.ExitCode()}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&tool, "tool", "", "tool to run: "+strings.Join(names, ", "))
+	cmd.Flags().BoolVar(&terminal, "terminal", false, "run the tool in a new terminal window instead")
+	cmd.MarkFlagRequired("tool")
+	cmd.RegisterFlagCompletionFunc("tool", cobra.FixedCompletions(names, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+// openTerminal starts argv in a new window of $TERMINAL, or of the
+// x-terminal-emulator of Debian like systems, and leaves it running
+func openTerminal(argv []string) error {
+	emulator := os.Getenv("TERMINAL")
+	if emulator == "" {
+		emulator = "x-terminal-emulator"
+	}
+	if _, err := exec.LookPath(emulator); err != nil {
+		return fmt.Errorf("no terminal to open, set $TERMINAL: %w", err)
+	}
+	window := exec.Command(emulator, append([]string{"-e"}, argv...)...)
+	if err := window.Start(); err != nil {
+		return err
+	}
+	return window.Process.Release()
+}
@@ -0,0 +1,38 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// zombieExitStatus returns the exit status of a process that has exited
+// but not been reaped yet, from the exit_code field of /proc/PID/stat
+func zombieExitStatus(pid int) (int, bool) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, false
+	}
+
+	// fields after the command name start with the state (field 3)
+	end := strings.LastIndex(string(data), ")")
+	if end == -1 {
+		return 0, false
+	}
+	fields := strings.Fields(string(data)[end+1:])
+	if len(fields) < 50 || fields[0] != "Z" {
+		return 0, false
+	}
+
+	status, err := strconv.Atoi(fields[49])
+	if err != nil {
+		return 0, false
+	}
+
+	// same encoding as wait(2)
+	if signal := status & 0x7f; signal != 0 {
+		return 128 + signal, true
+	}
+	return (status >> 8) & 0xff, true
+}
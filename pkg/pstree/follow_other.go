@@ -0,0 +1,8 @@
+//go:build !linux
+
+package pstree
+
+// zombieExitStatus is not available without /proc
+func zombieExitStatus(pid int) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,299 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// freezePrefix names the cgroups freeze creates for subtrees sharing
+	// their cgroup with other processes, e.g. pstree-freeze-1234
+	freezePrefix = "pstree-freeze-"
+
+	// how long the kernel gets to freeze every task of a cgroup
+	freezeTimeout = 5 * time.Second
+)
+
+// newFreezeCmd returns the freeze command, or thaw when thaw is set
+func (t *Tree) newFreezeCmd(thaw bool) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "freeze pid",
+		Short: "Freeze a process and all its descendants with the cgroup v2 freezer (Linux)",
+		Long: `freeze stops a subtree with the cgroup v2 freezer, which unlike a storm of
+SIGSTOP leaves no window for new children to escape and can't be undone
+by a SIGCONT from elsewhere. When the subtree is alone in its cgroup that
+cgroup is frozen, and thawed with thaw --force, otherwise it is moved into
+a new pstree-freeze-PID cgroup below its own first, which thaw resumes and
+moves back.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return &usageError{fmt.Errorf("invalid pid %q", args[0])}
+			}
+			if runtime.GOOS != "linux" {
+				return fmt.Errorf("the cgroup freezer needs Linux, use pstree stop")
+			}
+			mount := cgroupV2Mount()
+			if _, err := os.Stat(filepath.Join(mount, "cgroup.procs")); err != nil {
+				return fmt.Errorf("no cgroup v2 hierarchy at %s, use pstree stop", mount)
+			}
+			if thaw {
+				return thawCgroup(t.output, mount, pid, force)
+			}
+			return t.freezeSubtree(mount, pid)
+		},
+	}
+	if thaw {
+		cmd.Use = "thaw [--force] pid"
+		cmd.Short = "Thaw a subtree frozen with freeze (Linux)"
+		cmd.Long = `thaw resumes the pstree-freeze-PID cgroup of a process frozen with freeze,
+and moves its processes back to where they were. Other cgroups, frozen
+whole by freeze or by systemd, docker pause or the kubelet, are only
+thawed with --force.`
+		cmd.Flags().BoolVar(&force, "force", false, "thaw the cgroup of the process even when freeze didn't create it")
+	}
+	return cmd
+}
+
+// freezeSubtree freezes pid and its descendants, moving them into a
+// cgroup of their own first when they share theirs
+func (t *Tree) freezeSubtree(mount string, pid int) (err error) {
+	subtree, err := t.subtreePids(pid)
+	if err != nil {
+		return err
+	}
+	cgroup, err := processCgroup(pid)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(mount, cgroup)
+	if cgroup == "/" || !sameKeys(subtree, cgroupPids(dir)) {
+		// the subtree is moved as a whole, not out of several cgroups
+		for other := range subtree {
+			if otherCgroup, err := processCgroup(other); err == nil && otherCgroup != cgroup {
+				return fmt.Errorf("%d is in cgroup %s, not %s with %d, the subtree can't be frozen as one, use pstree stop", other, otherCgroup, cgroup, pid)
+			}
+		}
+		// no internal processes: a cgroup other than the root that hands
+		// controllers down to its children holds no processes itself
+		if controllers, _ := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control")); cgroup != "/" && len(strings.TrimSpace(string(controllers))) > 0 {
+			return fmt.Errorf("%s enables controllers for its children (%s), a cgroup for %d can't be created below it, use pstree stop", cgroup, strings.TrimSpace(string(controllers)), pid)
+		}
+		parent := dir
+		dir = filepath.Join(dir, freezePrefix+strconv.Itoa(pid))
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			return fmt.Errorf("%w, use pstree stop", err)
+		}
+		// a failure leaves nothing behind, the processes go back where
+		// they were
+		defer func() {
+			if err != nil {
+				writeCgroupFile(dir, "cgroup.freeze", "0")
+				if undo := moveBack(dir, parent); undo != nil {
+					err = fmt.Errorf("%w, undoing: %w", err, undo)
+				}
+			}
+		}()
+		// until no new child was forked outside, parents first so their
+		// next children start inside
+		for moved := true; moved; {
+			moved = false
+			for _, other := range t.subtreeOrder(pid) {
+				if otherCgroup, err := processCgroup(other); err == nil && filepath.Join(mount, otherCgroup) != dir {
+					err := writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(other))
+					switch {
+					// those that exited meanwhile are no error
+					case errors.Is(err, syscall.ESRCH):
+						continue
+					case errors.Is(err, syscall.EBUSY):
+						return fmt.Errorf("moving %d: %w, the cgroup v2 rule of no processes in cgroups with controllers for their children forbids it, use pstree stop", other, err)
+					case err != nil:
+						return fmt.Errorf("moving %d: %w", other, err)
+					}
+					moved = true
+				}
+			}
+			if moved {
+				if _, err := t.subtreePids(pid); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// a cgroup someone else froze is left frozen whatever happens
+	state, _ := os.ReadFile(filepath.Join(dir, "cgroup.freeze"))
+	if err := writeCgroupFile(dir, "cgroup.freeze", "1"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(state)) != "1" {
+		defer func() {
+			if err != nil {
+				writeCgroupFile(dir, "cgroup.freeze", "0")
+			}
+		}()
+	}
+	deadline := time.Now().Add(freezeTimeout)
+	for !cgroupFrozen(dir) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is not frozen after %s", dir, freezeTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Fprintf(t.output, "%d processes frozen in %s\n", len(cgroupPids(dir)), strings.TrimPrefix(dir, mount))
+	if !strings.HasPrefix(filepath.Base(dir), freezePrefix) {
+		fmt.Fprintf(t.output, "the whole cgroup was frozen, thaw it with pstree thaw --force %d\n", pid)
+	}
+	return nil
+}
+
+// thawCgroup thaws the cgroup of pid, and moves its processes back and
+// removes it when freeze created it. Other cgroups may have been frozen
+// by systemd, docker pause or the kubelet, they are only thawed with force
+func thawCgroup(out io.Writer, mount string, pid int, force bool) error {
+	cgroup, err := processCgroup(pid)
+	if err != nil {
+		return err
+	}
+	if cgroup == "/" {
+		return fmt.Errorf("%d is in the root cgroup, which is never frozen", pid)
+	}
+	created := strings.HasPrefix(path.Base(cgroup), freezePrefix)
+	if !created && !force {
+		return fmt.Errorf("%s was not created by pstree freeze, something else may have frozen it, use --force to thaw it anyway", cgroup)
+	}
+	dir := filepath.Join(mount, cgroup)
+	if err := writeCgroupFile(dir, "cgroup.freeze", "0"); err != nil {
+		return err
+	}
+	pids := cgroupPids(dir)
+	if !created {
+		fmt.Fprintf(out, "%d processes thawed in %s\n", len(pids), cgroup)
+		return nil
+	}
+
+	if err := moveBack(dir, filepath.Dir(dir)); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d processes thawed and moved back to %s\n", len(pids), path.Dir(cgroup))
+	return nil
+}
+
+// moveBack moves the processes of a cgroup freeze created to parent, the
+// cgroup they came from, and removes it
+func moveBack(dir, parent string) error {
+	for other := range cgroupPids(dir) {
+		// those that exited meanwhile are no error
+		if err := writeCgroupFile(parent, "cgroup.procs", strconv.Itoa(other)); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("moving %d back: %w", other, err)
+		}
+	}
+	return os.Remove(dir)
+}
+
+// subtreePids snapshots the process table and returns pid and its
+// descendants
+func (t *Tree) subtreePids(pid int) (map[int]bool, error) {
+	targets, err := t.resolveTargets(pid, true)
+	if err != nil {
+		return nil, err
+	}
+	for idx := t.getPidIndex(myPID); idx != -1; idx = t.procs[idx].ParentIdx {
+		if t.procs[idx].PID == pid {
+			return nil, fmt.Errorf("pstree runs below %d", pid)
+		}
+	}
+	pids := map[int]bool{}
+	for _, idx := range targets {
+		pids[t.procs[idx].PID] = true
+	}
+	return pids, nil
+}
+
+// subtreeOrder returns the pids of the last snapshot of the subtree of
+// pid, parents first
+func (t *Tree) subtreeOrder(pid int) []int {
+	idx := t.getPidIndex(pid)
+	if idx == -1 {
+		return nil
+	}
+	var pids []int
+	for _, i := range t.subtreeIndices(idx) {
+		pids = append(pids, t.procs[i].PID)
+	}
+	return pids
+}
+
+// processCgroup returns the cgroup v2 path of a process, e.g.
+// /user.slice/user-1000.slice/session-2.scope
+func processCgroup(pid int) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	cgroup, ok := parseCgroupFile(string(data))[""]
+	if !ok {
+		return "", fmt.Errorf("%d is in no cgroup v2", pid)
+	}
+	return cgroup, nil
+}
+
+// cgroupPids returns the processes of a cgroup and of the cgroups below
+func cgroupPids(dir string) map[int]bool {
+	pids := map[int]bool{}
+	filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.Name() != "cgroup.procs" {
+			return nil
+		}
+		data, _ := os.ReadFile(p)
+		for _, field := range strings.Fields(string(data)) {
+			if pid, err := strconv.Atoi(field); err == nil {
+				pids[pid] = true
+			}
+		}
+		return nil
+	})
+	return pids
+}
+
+// cgroupFrozen reports whether every task of a cgroup is frozen, from
+// the "frozen 1" line of cgroup.events
+func cgroupFrozen(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.events"))
+	return err == nil && strings.Contains(string(data), "frozen 1")
+}
+
+// writeCgroupFile writes a value to a control file of a cgroup
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644)
+}
+
+// sameKeys reports whether two sets hold the same pids
+func sameKeys(a, b map[int]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func FuzzParsePsTable(f *testing.F) {
+	if seed, err := os.ReadFile(filepath.Join("testdata", "ps-ef.txt")); err == nil {
+		f.Add(string(seed))
+	}
+	f.Add("PID PPID CMD\n1 0 init\n")
+	f.Add("  PID  PPID  PGID USER     NLWP COMMAND\n   12     1    12 0           3 sh -c 'a  b'\n\n 13\n")
+	f.Add("UID PID PPID\nroot x 1\n")
+	f.Add("PID PPID\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tr := newTree()
+		tr.config.NumericOwners = true
+		list, err := tr.parsePsTable(strings.NewReader(input))
+		if err != nil {
+			return
+		}
+		// one process per line at most, after the header
+		if lines := strings.Count(input, "\n") + 1; len(list) > lines-1 {
+			t.Fatalf("%d processes from %d lines", len(list), lines)
+		}
+		for _, process := range list {
+			if process.ThreadCount < 1 {
+				t.Fatalf("pid %d has %d threads", process.PID, process.ThreadCount)
+			}
+			if process.ParentIdx != -1 || process.ChildIdx != -1 || process.SisterIdx != -1 {
+				t.Fatalf("pid %d is linked before the tree is built", process.PID)
+			}
+		}
+	})
+}
+
+func FuzzParseProcStat(f *testing.F) {
+	if seed, err := os.ReadFile(filepath.Join("testdata", "proc", "1211", "stat")); err == nil {
+		f.Add(string(seed))
+	}
+	f.Add("1 (systemd) S 0 1 1 0 -1 4194560")
+	f.Add("42 (a) Z 1 42")
+	f.Add("7 () R")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var proc Process
+		if !parseProcStat(data, &proc) {
+			return
+		}
+		open := strings.IndexByte(data, '(')
+		pid, err := strconv.Atoi(strings.TrimSpace(data[:open]))
+		if err != nil || pid != proc.PID {
+			t.Fatalf("pid %d parsed from %q", proc.PID, data)
+		}
+	})
+}
+
+// FuzzParseProcStatComm checks that no command name shifts the fields
+// that follow it
+func FuzzParseProcStatComm(f *testing.F) {
+	for _, comm := range trickyComms {
+		f.Add(comm, 1, 2)
+	}
+
+	f.Fuzz(func(t *testing.T, comm string, ppid, pgid int) {
+		data := statLine(4242, comm, ppid, pgid)
+		var proc Process
+		if !parseProcStat(data, &proc) {
+			t.Fatalf("%q not parsed", data)
+		}
+		if proc.PID != 4242 || proc.Comm != comm || proc.State != "S" || proc.PPID != ppid || proc.PGID != pgid {
+			t.Fatalf("%q parsed as pid %d comm %q state %q ppid %d pgid %d", data, proc.PID, proc.Comm, proc.State, proc.PPID, proc.PGID)
+		}
+	})
+}
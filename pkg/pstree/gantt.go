@@ -0,0 +1,188 @@
+package pstree
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	ganttLabelWidth = 32
+	ganttSVGWidth   = 1000
+	ganttSVGRow     = 18
+)
+
+// lifetime is the span during which one process was seen in a history
+type lifetime struct {
+	PID       int
+	PPID      int
+	StartTime uint64
+	Cmd       string
+	// first time seen, and first time missing (or the end of the history)
+	First time.Time
+	Last  time.Time
+
+	lastSeen int
+	parent   *lifetime
+	children []*lifetime
+	depth    int
+}
+
+// collectLifetimes turns a history into process lifetimes ordered like
+// the tree: every process is followed by its children, by first seen time
+func collectLifetimes(history []TableSnapshot) []*lifetime {
+	type key struct {
+		pid   int
+		start uint64
+	}
+	seen := map[key]*lifetime{}
+	byPID := map[int][]*lifetime{}
+	var all []*lifetime
+
+	for i, snap := range history {
+		for _, p := range snap.Processes {
+			k := key{p.PID, p.StartTime}
+			if lt, ok := seen[k]; ok {
+				lt.lastSeen = i
+				continue
+			}
+			lt := &lifetime{PID: p.PID, PPID: p.PPID, StartTime: p.StartTime, Cmd: p.Cmd, First: snap.Time, lastSeen: i}
+			seen[k] = lt
+			byPID[p.PID] = append(byPID[p.PID], lt)
+			all = append(all, lt)
+		}
+	}
+
+	// a process seen in a snapshot is assumed alive until the next one
+	for _, lt := range all {
+		if lt.lastSeen+1 < len(history) {
+			lt.Last = history[lt.lastSeen+1].Time
+		} else {
+			lt.Last = history[lt.lastSeen].Time
+		}
+	}
+
+	// the parent is the latest process with that PID seen before the child
+	var roots []*lifetime
+	for _, lt := range all {
+		for _, candidate := range byPID[lt.PPID] {
+			if candidate != lt && !candidate.First.After(lt.First) {
+				lt.parent = candidate
+			}
+		}
+		if lt.parent == nil {
+			roots = append(roots, lt)
+		} else {
+			lt.parent.children = append(lt.parent.children, lt)
+		}
+	}
+
+	// depth first with an explicit stack, siblings by start time
+	byStart := func(lts []*lifetime) {
+		sort.SliceStable(lts, func(a, b int) bool {
+			if !lts[a].First.Equal(lts[b].First) {
+				return lts[a].First.Before(lts[b].First)
+			}
+			return lts[a].PID < lts[b].PID
+		})
+	}
+	var ordered []*lifetime
+	byStart(roots)
+	stack := slices.Clone(roots)
+	slices.Reverse(stack)
+	for len(stack) > 0 {
+		lt := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if lt.parent != nil {
+			lt.depth = lt.parent.depth + 1
+		}
+		ordered = append(ordered, lt)
+		byStart(lt.children)
+		for i := len(lt.children) - 1; i >= 0; i-- {
+			stack = append(stack, lt.children[i])
+		}
+	}
+	return ordered
+}
+
+func (lt *lifetime) label() string {
+	return fmt.Sprintf("%s%d %s", strings.Repeat(" ", lt.depth), lt.PID, commandName(lt.Cmd))
+}
+
+// printGantt draws one bar per process lifetime over the recorded span
+func (t *Tree) printGantt(w io.Writer, history []TableSnapshot) {
+	lifetimes := collectLifetimes(history)
+	begin, end := history[0].Time, history[len(history)-1].Time
+	span := end.Sub(begin)
+
+	bar, empty := "#", "."
+	if unicodeGraphics(t.config.Graphics) {
+		bar, empty = "█", "·"
+	}
+
+	cols := t.config.Columns - ganttLabelWidth - 2
+	if cols < 10 || cols > 200 {
+		cols = 80
+	}
+
+	fmt.Fprintf(w, "%-*s %s .. %s (%s)\n", ganttLabelWidth, "PROCESS", begin.Format(time.TimeOnly), end.Format(time.TimeOnly), span.Round(time.Second))
+	for _, lt := range lifetimes {
+		// cut and padded by display width, command names may be wide
+		label := runewidth.FillRight(runewidth.Truncate(lt.label(), ganttLabelWidth, ""), ganttLabelWidth)
+
+		var line strings.Builder
+		for c := 0; c < cols; c++ {
+			at := begin
+			if span > 0 {
+				at = begin.Add(time.Duration((float64(c) + 0.5) / float64(cols) * float64(span)))
+			}
+			if span == 0 || (!at.Before(lt.First) && at.Before(lt.Last)) || (at.Equal(lt.Last) && lt.Last.Equal(end)) {
+				line.WriteString(bar)
+			} else {
+				line.WriteString(empty)
+			}
+		}
+		fmt.Fprintf(w, "%s %s\n", label, line.String())
+	}
+}
+
+// printGanttSVG writes the same chart as a standalone SVG document
+func printGanttSVG(w io.Writer, history []TableSnapshot) {
+	lifetimes := collectLifetimes(history)
+	begin, end := history[0].Time, history[len(history)-1].Time
+	span := end.Sub(begin)
+
+	const labelPx = 280
+	barsPx := float64(ganttSVGWidth - labelPx - 10)
+	x := func(t time.Time) float64 {
+		if span == 0 {
+			return 0
+		}
+		return float64(t.Sub(begin)) / float64(span) * barsPx
+	}
+
+	height := (len(lifetimes) + 2) * ganttSVGRow
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n", ganttSVGWidth, height)
+	fmt.Fprintf(w, `<text x="4" y="%d">%s .. %s (%s)</text>`+"\n", ganttSVGRow-5, begin.Format(time.TimeOnly), end.Format(time.TimeOnly), span.Round(time.Second))
+
+	for i, lt := range lifetimes {
+		y := (i + 1) * ganttSVGRow
+		width := x(lt.Last) - x(lt.First)
+		if span == 0 {
+			width = barsPx
+		}
+		if width < 2 {
+			width = 2
+		}
+		fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", 4+lt.depth*8, y+ganttSVGRow-5, html.EscapeString(fmt.Sprintf("%d %s", lt.PID, commandName(lt.Cmd))))
+		fmt.Fprintf(w, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#4a90d9"><title>%s (%s)</title></rect>`+"\n",
+			labelPx+x(lt.First), y+3, width, ganttSVGRow-6, html.EscapeString(lt.Cmd), lt.Last.Sub(lt.First).Round(time.Second))
+	}
+	fmt.Fprintln(w, "</svg>")
+}
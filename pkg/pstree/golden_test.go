@@ -0,0 +1,97 @@
+package pstree
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+const (
+	procFixture = "--source=procfs:testdata/proc"
+	psFixture   = "--source=file:testdata/ps-ef.txt"
+)
+
+// goldenCases render the fixtures of testdata, testdata/golden/NAME
+// holds the expected output of each
+var goldenCases = []struct {
+	name string
+	args []string
+}{
+	{"ascii", []string{procFixture, "-g0", "-a"}},
+	{"pc850", []string{procFixture, "-g1", "-a"}},
+	{"vt100", []string{procFixture, "-g2", "-a"}},
+	{"utf8", []string{procFixture, "-g3", "-a"}},
+	{"rounded", []string{procFixture, "-g", "rounded", "-a"}},
+	{"heavy", []string{procFixture, "-g", "heavy", "-a"}},
+	{"double", []string{procFixture, "-g", "double", "-a"}},
+	{"custom", []string{procFixture, "-g", "custom", "-a"}},
+	{"kernel", []string{procFixture, "-g3", "2"}},
+	{"search", []string{procFixture, "-g0", "make"}},
+	{"level", []string{procFixture, "-g0", "-a", "-l2", "--counts"}},
+	{"exclude", []string{procFixture, "-g0", "-a", "--exclude", "postgres"}},
+	{"columns", []string{procFixture, "-g0", "-a", "-o", "pid,ppid,user,state,tty,cmd"}},
+	{"header-row", []string{procFixture, "-g3", "-a", "--header-row", "-o", "pid,user,threads"}},
+	{"truncated", []string{procFixture, "-g0", "-a", "--columns", "40"}},
+	{"by-user", []string{procFixture, "-g0", "-a", "--by-user"}},
+	{"summary", []string{procFixture, "-g3", "-a", "--summary", "--legend"}},
+	{"dot", []string{procFixture, "--output", "dot", "1211"}},
+	{"ps-ascii", []string{psFixture, "-g0", "-a"}},
+	{"ps-utf8", []string{psFixture, "-g3", "sshd"}},
+	{"ps-json", []string{psFixture, "--output", "json", "1211"}},
+}
+
+// renderFixture renders like the pstree command line args. Owners are
+// numeric, the width is fixed and -u doesn't default to the user running
+// the tests, the output doesn't depend on the host
+func renderFixture(tb testing.TB, args []string) string {
+	tb.Helper()
+	tr := newTree()
+	cmd := &cobra.Command{}
+	tr.addTreeFlags(cmd)
+	cmd.Flags().StringVar(&tr.config.Output, "output", "text", "")
+	args = append([]string{"--numeric-owners", "--columns", "100", "-u", "65534"}, args...)
+	if err := cmd.ParseFlags(args); err != nil {
+		tb.Fatal(err)
+	}
+	if err := tr.setupConfig(cmd.Flags().Args()); err != nil {
+		tb.Fatal(err)
+	}
+	if err := tr.loadProcesses(); err != nil {
+		tb.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	tr.output = &out
+	if _, err := tr.renderProcesses(cmd.Flags().Args()); err != nil {
+		tb.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderFixture(t, tc.args)
+			path := filepath.Join("testdata", "golden", tc.name)
+			if *updateGolden {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -update to create it", err)
+			}
+			if got != string(want) {
+				t.Errorf("%v renders\n%s\nwant\n%s", tc.args, got, want)
+			}
+		})
+	}
+}
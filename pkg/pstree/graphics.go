@@ -0,0 +1,106 @@
+package pstree
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// graphicsNames name the treeChars sets for --graphics, which also takes
+// their index
+var graphicsNames = []string{"ascii", "pc850", "vt100", "utf8", "rounded", "heavy", "double", "custom"}
+
+// customGlyphs are the keys of the custom-graphics section of the config
+// file, and the glyphs of the custom set they change
+var customGlyphs = map[string]*string{
+	"leaf":       &treeChars[GraphicsCustom].S2,
+	"parent":     &treeChars[GraphicsCustom].P,
+	"leader":     &treeChars[GraphicsCustom].PGL,
+	"non-leader": &treeChars[GraphicsCustom].NPGL,
+	"branch":     &treeChars[GraphicsCustom].BarC,
+	"bar":        &treeChars[GraphicsCustom].Bar,
+	"last":       &treeChars[GraphicsCustom].BarL,
+}
+
+// graphicsValue is the value of --graphics, a set by name or index
+type graphicsValue struct {
+	graphics *int
+}
+
+func (v graphicsValue) String() string {
+	if *v.graphics < 0 || *v.graphics >= len(graphicsNames) {
+		return strconv.Itoa(*v.graphics)
+	}
+	return graphicsNames[*v.graphics]
+}
+
+func (v graphicsValue) Set(s string) error {
+	graphics := slices.Index(graphicsNames, strings.ToLower(s))
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(treeChars) {
+		graphics = n
+	}
+	if graphics == -1 {
+		return fmt.Errorf("expected one of %s, or 0 to %d", strings.Join(graphicsNames, ", "), len(treeChars)-1)
+	}
+	*v.graphics = graphics
+	return nil
+}
+
+func (v graphicsValue) Type() string { return "string" }
+
+// addGraphicsFlag adds -g/--graphics to a command that draws trees
+func (t *Tree) addGraphicsFlag(cmd *cobra.Command) {
+	t.config.Graphics = isUnicodeTerminal()
+	cmd.Flags().VarP(graphicsValue{&t.config.Graphics}, "graphics", "g", "tree glyphs: "+strings.Join(graphicsNames, ", ")+", or 0=ASCII, 1=IBM-850, 2=VT100, 3=UTF-8")
+	cmd.RegisterFlagCompletionFunc("graphics", cobra.FixedCompletions(graphicsNames, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// setupGraphics checks --graphics and picks its glyphs
+func (t *Tree) setupGraphics() error {
+	if t.config.Graphics < 0 || t.config.Graphics >= len(treeChars) {
+		return fmt.Errorf("invalid graphics %d, expected 0 to %d", t.config.Graphics, len(treeChars)-1)
+	}
+	t.config.TreeChar = &treeChars[t.config.Graphics]
+	return nil
+}
+
+// unicodeGraphics reports whether a glyph set is drawn with UTF-8, so
+// the rest of the output may use it too
+func unicodeGraphics(graphics int) bool {
+	if graphics < 0 || graphics >= len(treeChars) {
+		return false
+	}
+	chars := treeChars[graphics]
+	glyphs := chars.S2 + chars.P + chars.PGL + chars.NPGL + chars.BarC + chars.Bar + chars.BarL
+	// the IBM-850 glyphs are no UTF-8
+	return utf8.ValidString(glyphs) && strings.ContainsFunc(glyphs, func(r rune) bool { return r >= utf8.RuneSelf })
+}
+
+// applyCustomGraphics sets the glyphs of --graphics custom from the
+// custom-graphics section of the config file
+func applyCustomGraphics(path string, section any) error {
+	if section == nil {
+		return nil
+	}
+	glyphs, ok := section.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: custom-graphics: expected names and glyphs", path)
+	}
+	for name, glyph := range glyphs {
+		field, ok := customGlyphs[name]
+		if !ok {
+			names := make([]string, 0, len(customGlyphs))
+			for known := range customGlyphs {
+				names = append(names, known)
+			}
+			slices.Sort(names)
+			return fmt.Errorf("%s: custom-graphics: unknown glyph %q, expected some of %s", path, name, strings.Join(names, ", "))
+		}
+		*field = fmt.Sprint(glyph)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package pstree
+
+// insertGroups inserts a synthetic node between a process and those of
+// its children whose label differs from its own, so the processes that
+// share a label sit together under a node named after it. Processes
+// with an empty label, or whose parent has none, are left alone.
+// Synthetic nodes get negative pids, -1 is taken by "no pid"
+func (t *Tree) insertGroups(labels map[int]string, name func(label string) string) {
+	type groupKey struct {
+		parent int
+		label  string
+	}
+	groups := map[groupKey]int{}
+	nextPID := t.nextGroupPID()
+
+	for i := range t.nProc {
+		process := &t.procs[i]
+		label := labels[process.PID]
+		if label == "" || process.Thread {
+			continue
+		}
+		parentLabel, ok := labels[process.PPID]
+		if !ok || parentLabel == label {
+			continue
+		}
+
+		key := groupKey{process.PPID, label}
+		groupPID, ok := groups[key]
+		if !ok {
+			groupPID = nextPID
+			nextPID--
+			groups[key] = groupPID
+			t.procs = append(t.procs, Process{
+				PID:       groupPID,
+				PPID:      process.PPID,
+				Cmd:       name(label),
+				Group:     true,
+				ParentIdx: -1,
+				ChildIdx:  -1,
+				SisterIdx: -1,
+			})
+			// procs may have moved
+			process = &t.procs[i]
+		}
+		process.PPID = groupPID
+	}
+	t.indexProcesses()
+}
+
+// nextGroupPID returns the pid of the next synthetic node, below those
+// of the existing ones
+func (t *Tree) nextGroupPID() int {
+	pid := -2
+	for _, p := range t.procs {
+		if p.Group {
+			pid = min(pid, p.PID-1)
+		}
+	}
+	return pid
+}
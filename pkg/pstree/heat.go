@@ -0,0 +1,65 @@
+package pstree
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// validateHeat checks --heat, which needs the values it colors by
+func (t *Tree) validateHeat() error {
+	switch t.config.Heat {
+	case "":
+	case "cpu":
+		if !t.config.ShowCPU && !t.columnShown("cpu") {
+			return fmt.Errorf("--heat cpu needs --cpu")
+		}
+	case "mem":
+		if !t.config.ShowMem && !t.columnShown("rss") {
+			return fmt.Errorf("--heat mem needs --mem")
+		}
+	default:
+		return fmt.Errorf("invalid --heat %q, expected cpu or mem", t.config.Heat)
+	}
+	return nil
+}
+
+// heatValue is the cpu usage or resident memory of a process, of its
+// whole subtree with --cumulative
+func (t *Tree) heatValue(process Process) float64 {
+	switch {
+	case t.config.Heat == "cpu" && t.config.Cumulative:
+		return process.SubtreeCPU
+	case t.config.Heat == "cpu":
+		return process.CPUPercent
+	case t.config.Cumulative:
+		return float64(process.SubtreeRSS)
+	}
+	return float64(process.RSS)
+}
+
+// rankHeat finds the highest --heat value of the printed processes, the
+// red end of the gradient
+func (t *Tree) rankHeat() {
+	t.heatMax = 0
+	for _, process := range t.procs {
+		if process.Print && !process.Group && !process.Thread {
+			t.heatMax = max(t.heatMax, t.heatValue(process))
+		}
+	}
+}
+
+// heatStyle colors a process from green to red, through yellow, by its
+// --heat value relative to the highest
+func (t *Tree) heatStyle(process Process) (lipgloss.Style, bool) {
+	if process.Group || process.Thread {
+		return lipgloss.Style{}, false
+	}
+	var heat float64
+	if t.heatMax > 0 {
+		heat = min(t.heatValue(process)/t.heatMax, 1)
+	}
+	red := int(255 * min(2*heat, 1))
+	green := int(255 * min(2*(1-heat), 1))
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(fmt.Sprintf("#%02x%02x00", red, green))), true
+}
@@ -0,0 +1,99 @@
+package pstree
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// highlight levels of a process
+const (
+	highlightNone = iota
+	// an ancestor of a highlighted process
+	highlightAncestor
+	// a process matching --highlight or -H, or pstree itself with -h
+	highlightMatch
+)
+
+var (
+	// lipgloss drops the styling when stdout is not a terminal
+	highlightMatchStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+	highlightAncestorStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// compileHighlights prepares the --highlight patterns for markHighlights
+func (t *Tree) compileHighlights() error {
+	var err error
+	t.highlightPatterns, err = t.compilePatterns(t.config.Highlights)
+	return err
+}
+
+// markHighlights flags the processes matching --highlight or -H, or
+// pstree itself with -h, and their ancestors
+func (t *Tree) markHighlights() {
+	for i := range t.procs {
+		t.procs[i].Highlight = highlightNone
+	}
+	if len(t.highlightPatterns) == 0 && len(t.config.HighlightPids) == 0 && !t.config.HighlightSelf {
+		return
+	}
+	for i := range t.procs {
+		process := t.procs[i]
+		if process.Group || !t.highlighted(process) {
+			continue
+		}
+		t.procs[i].Highlight = highlightMatch
+		for parent := process.ParentIdx; parent != -1 && t.procs[parent].Highlight == highlightNone; parent = t.procs[parent].ParentIdx {
+			t.procs[parent].Highlight = highlightAncestor
+		}
+	}
+}
+
+// highlighted reports whether a process is highlighted itself
+func (t *Tree) highlighted(process Process) bool {
+	if process.PID == myPID {
+		// our own command line holds the patterns
+		return t.config.HighlightSelf || slices.Contains(t.config.HighlightPids, myPID)
+	}
+	return slices.Contains(t.config.HighlightPids, process.PID) || matchesAny(t.highlightPatterns, t.matchText(process))
+}
+
+// lineStyle picks the style of the line of a process: the highlights
+// first, then the real-time, audit, OOM risk and --heat colors, then the
+// state colors
+func (t *Tree) lineStyle(process Process) (lipgloss.Style, bool) {
+	switch process.Highlight {
+	case highlightMatch:
+		return highlightMatchStyle, true
+	case highlightAncestor:
+		return highlightAncestorStyle, true
+	}
+	if t.config.Sched && isRealTime(process) {
+		return realTimeStyle, true
+	}
+	if t.config.Audit {
+		if style, ok := auditStyle(process); ok {
+			return style, true
+		}
+	}
+	if t.config.OOM {
+		if style, ok := t.oomStyle(process); ok {
+			return style, true
+		}
+	}
+	if t.config.Heat != "" {
+		if style, ok := t.heatStyle(process); ok {
+			return style, true
+		}
+	}
+	return stateStyle(process)
+}
+
+// highlightLine styles a rendered line per lineStyle
+func (t *Tree) highlightLine(process Process, line string) string {
+	style, ok := t.lineStyle(process)
+	if !ok {
+		return line
+	}
+	return renderLines(style, line)
+}
@@ -0,0 +1,82 @@
+package pstree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TableSnapshot is one capture of the process table, as recorded
+type TableSnapshot struct {
+	Time      time.Time `json:"time"`
+	Processes []Process `json:"processes"`
+}
+
+// readHistory loads a recorded history: one JSON encoded TableSnapshot per
+// line, in chronological order. "-" reads from stdin
+func readHistory(path string) ([]TableSnapshot, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var history []TableSnapshot
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snap TableSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snap); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		history = append(history, snap)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%s: no snapshots recorded", path)
+	}
+	return history, nil
+}
+
+// currentSnapshot captures the processes currently loaded
+func (t *Tree) currentSnapshot() TableSnapshot {
+	return TableSnapshot{Time: time.Now(), Processes: append([]Process(nil), t.procs...)}
+}
+
+// writeSnapshot appends a snapshot to a history as a single JSON line
+func writeSnapshot(w io.Writer, snap TableSnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// useSnapshot replaces the loaded processes with a recorded snapshot,
+// ready to go through the usual hierarchy, filter and render passes
+func (t *Tree) useSnapshot(snap TableSnapshot) {
+	t.procs = make([]Process, len(snap.Processes))
+	for i, p := range snap.Processes {
+		p.ParentIdx = -1
+		p.ChildIdx = -1
+		p.SisterIdx = -1
+		p.Print = false
+		t.procs[i] = p
+	}
+	t.indexProcesses()
+	t.reconcileParents()
+	t.breakCycles()
+}
@@ -0,0 +1,78 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readIO reads the bytes a process read from and wrote to storage, from
+// /proc/PID/io, which needs the rights to ptrace the process
+func readIO(procDir string) (read, write uint64, ok bool) {
+	data, err := os.ReadFile(filepath.Join(procDir, "io"))
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "read_bytes":
+			read = n
+		case "write_bytes":
+			write = n
+		}
+	}
+	return read, write, true
+}
+
+// ioRates turns the io counters of successive watch refreshes into rates
+type ioRates struct {
+	prev map[procKey][2]uint64
+	at   time.Time
+}
+
+// update sets ReadRate and WriteRate of the loaded processes, from the
+// counters of the previous refresh
+func (r *ioRates) update(t *Tree) {
+	now := time.Now()
+	current := make(map[procKey][2]uint64, len(t.procs))
+	elapsed := now.Sub(r.at).Seconds()
+	for i := range t.procs {
+		p := &t.procs[i]
+		if !p.IOKnown {
+			continue
+		}
+		key := procKey{p.PID, p.StartTime}
+		current[key] = [2]uint64{p.ReadBytes, p.WriteBytes}
+		if prev, ok := r.prev[key]; ok && elapsed > 0 {
+			p.ReadRate = float64(p.ReadBytes-min(prev[0], p.ReadBytes)) / elapsed
+			p.WriteRate = float64(p.WriteBytes-min(prev[1], p.WriteBytes)) / elapsed
+			p.IORates = true
+		}
+	}
+	r.prev = current
+	r.at = now
+}
+
+// ioAnnotation returns the storage io of a process, followed in watch mode
+// by its rates, e.g. "(io r 1.2G w 30M)" or "(io r 1.2G +40K/s w 30M +0B/s)"
+func ioAnnotation(process Process) string {
+	if !process.IOKnown {
+		return "(io ?)"
+	}
+	if process.IORates {
+		return fmt.Sprintf("(io r %s +%s/s w %s +%s/s)", humanSize(process.ReadBytes), humanSize(uint64(process.ReadRate)),
+			humanSize(process.WriteBytes), humanSize(uint64(process.WriteRate)))
+	}
+	return fmt.Sprintf("(io r %s w %s)", humanSize(process.ReadBytes), humanSize(process.WriteBytes))
+}
@@ -0,0 +1,65 @@
+package pstree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes, matching the kernel's IOPRIO_CLASS_* values
+const (
+	IOPrioClassNone = iota
+	IOPrioClassRealtime
+	IOPrioClassBestEffort
+	IOPrioClassIdle
+)
+
+// IOPriority is an I/O scheduling class and level (0 highest, 7 lowest)
+type IOPriority struct {
+	Class int
+	Level int
+}
+
+func (p IOPriority) String() string {
+	switch p.Class {
+	case IOPrioClassRealtime:
+		return fmt.Sprintf("realtime:%d", p.Level)
+	case IOPrioClassBestEffort:
+		return fmt.Sprintf("best-effort:%d", p.Level)
+	case IOPrioClassIdle:
+		return "idle"
+	}
+	return "none"
+}
+
+// parseIOPriority accepts the ionice(1) style class names, optionally
+// followed by ":level", e.g. "idle", "be:7" or "realtime:0"
+func parseIOPriority(s string) (IOPriority, error) {
+	name, levelStr, hasLevel := strings.Cut(strings.ToLower(s), ":")
+
+	var prio IOPriority
+	switch name {
+	case "none", "0":
+		prio.Class = IOPrioClassNone
+	case "realtime", "rt", "1":
+		prio.Class = IOPrioClassRealtime
+	case "best-effort", "be", "2":
+		prio.Class = IOPrioClassBestEffort
+	case "idle", "3":
+		prio.Class = IOPrioClassIdle
+	default:
+		return prio, fmt.Errorf("unknown I/O class %q (want none, realtime, best-effort or idle)", name)
+	}
+
+	if prio.Class == IOPrioClassBestEffort || prio.Class == IOPrioClassRealtime {
+		prio.Level = 4
+	}
+	if hasLevel {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level < 0 || level > 7 {
+			return prio, fmt.Errorf("invalid I/O priority level %q (want 0-7)", levelStr)
+		}
+		prio.Level = level
+	}
+	return prio, nil
+}
@@ -0,0 +1,22 @@
+package pstree
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// setIOPriority applies an I/O scheduling class with ioprio_set(2)
+func setIOPriority(pid int, prio IOPriority) error {
+	value := prio.Class<<ioprioClassShift | prio.Level
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(value))
+	if errno != 0 {
+		return syscall.Errno(errno)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package pstree
+
+import "fmt"
+
+func setIOPriority(pid int, prio IOPriority) error {
+	return fmt.Errorf("I/O priorities are only supported on Linux")
+}
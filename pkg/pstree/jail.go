@@ -0,0 +1,83 @@
+package pstree
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// jailNames lists the FreeBSD jails, "jls jid name" prints "1 www"
+func (t *Tree) jailNames() map[int]string {
+	if t.jailNameCache == nil {
+		t.jailNameCache = listIsolation(" ", "jls", "jid", "name")
+	}
+	return t.jailNameCache
+}
+
+// zoneNames lists the Solaris/illumos zones, "zoneadm list -p" prints
+// "0:global:running:/::..." for each running zone
+func (t *Tree) zoneNames() map[int]string {
+	if t.zoneNameCache == nil {
+		t.zoneNameCache = listIsolation(":", "zoneadm", "list", "-p")
+	}
+	return t.zoneNameCache
+}
+
+// listIsolation runs a command printing one "ID<sep>NAME..." line per jail
+// or zone. Nothing is listed where the command doesn't exist
+func listIsolation(sep string, name string, args ...string) map[int]string {
+	names := map[int]string{}
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return names
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), sep)
+		if len(fields) < 2 {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil {
+			names[id] = fields[1]
+		}
+	}
+	return names
+}
+
+// isolationMatches reports whether a jail or zone id is the one given
+// to --jail or --zone, by name or by id
+func isolationMatches(want string, id int, names map[int]string) bool {
+	if want == strconv.Itoa(id) {
+		return true
+	}
+	name, ok := names[id]
+	return ok && name == want
+}
+
+// isolationName names a jail or zone, by id when it has no known name
+func isolationName(id int, names map[int]string) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return strconv.Itoa(id)
+}
+
+// groupByJail inserts a "jail NAME" or "zone NAME" node above the
+// processes of each FreeBSD jail or Solaris zone, the host and the
+// global zone are left alone
+func (t *Tree) groupByJail() {
+	labels := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if p.Thread {
+			continue
+		}
+		switch {
+		case p.Jail != 0:
+			labels[p.PID] = "jail " + isolationName(p.Jail, t.jailNames())
+		case p.Zone != 0:
+			labels[p.PID] = "zone " + isolationName(p.Zone, t.zoneNames())
+		default:
+			labels[p.PID] = ""
+		}
+	}
+	t.insertGroups(labels, func(label string) string { return label })
+}
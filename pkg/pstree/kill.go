@@ -0,0 +1,404 @@
+package pstree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+func (t *Tree) newKillCmd() *cobra.Command {
+	var (
+		signal   string
+		escalate string
+		dryRun   bool
+		yes      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "kill [--signal SIGNAL | --escalate TERM:5s,KILL] [--dry-run] [--yes] pid|pattern ...",
+		Short: "Signal processes and all their descendants, children first",
+		Long: `kill resolves the subtrees of the pids and of the processes matching the
+patterns, prints them, asks for confirmation and signals every process,
+children before their parents, so no parent gets to respawn a child.
+pstree itself and the processes it runs below are never signaled.
+
+--escalate sends the signals in turn, each to the processes still running
+after the wait of the previous one, and reports what became of each, e.g.
+--escalate TERM:5s,KILL kills what didn't exit within 5s of SIGTERM.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sig, sigName, err := parseSignal(signal)
+			if err != nil {
+				return &usageError{err}
+			}
+			steps := []killStep{{sig: sig, name: sigName}}
+			if escalate != "" {
+				if cmd.Flags().Changed("signal") {
+					return &usageError{fmt.Errorf("--signal and --escalate don't go together")}
+				}
+				if steps, err = parseEscalation(escalate); err != nil {
+					return &usageError{err}
+				}
+			}
+			if err := t.setupGraphics(); err != nil {
+				return &usageError{err}
+			}
+			if err := validateMatchField(t.config.MatchField); err != nil {
+				return &usageError{err}
+			}
+
+			roots, targets, missing, err := t.resolveSubtrees(args, true)
+			if err != nil {
+				return err
+			}
+			warnMissing(missing)
+			if len(targets) == 0 {
+				fmt.Fprintln(os.Stderr, "pstree: no matching processes")
+				return &exitStatusError{Code: 1}
+			}
+			t.printSubtrees(roots)
+
+			if dryRun {
+				fmt.Fprintf(t.output, "%d processes would be sent %s\n", len(targets), describeSteps(steps))
+				return nil
+			}
+			if !yes {
+				ok, err := confirm(fmt.Sprintf("send %s to these %d processes?", describeSteps(steps), len(targets)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return &exitStatusError{Code: 1}
+				}
+			}
+			return t.killSubtrees(targets, steps, escalate != "")
+		},
+	}
+
+	cmd.Flags().StringVarP(&signal, "signal", "s", defaultKillSignal, "signal to send, by name or number, e.g. TERM, HUP, KILL or 9; TERM by default, KILL on Windows, which has no other")
+	cmd.Flags().StringVar(&escalate, "escalate", "", "signals to send in turn with the time to wait for the processes to exit after each, e.g. TERM:5s,KILL")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be signaled")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+	cmd.Flags().BoolVarP(&t.config.Regex, "regex", "e", false, "match the patterns as regular expressions against command lines")
+	cmd.Flags().BoolVar(&t.config.IgnoreCase, "ignore-case", false, "match the patterns case-insensitively")
+	cmd.Flags().StringVar(&t.config.MatchField, "match-field", "cmdline", "what patterns are matched against: comm, exe or cmdline")
+	t.addGraphicsFlag(cmd)
+
+	return cmd
+}
+
+// killStep is a signal of --escalate and the time the processes get to
+// exit after it, before the next one
+type killStep struct {
+	sig  os.Signal
+	name string
+	wait time.Duration
+}
+
+// parseEscalation parses the --escalate steps, e.g. TERM:5s,KILL. Every
+// step but the last needs a wait
+func parseEscalation(spec string) ([]killStep, error) {
+	parts := strings.Split(spec, ",")
+	steps := make([]killStep, 0, len(parts))
+	for i, part := range parts {
+		name, wait, hasWait := strings.Cut(strings.TrimSpace(part), ":")
+		sig, sigName, err := parseSignal(name)
+		if err != nil {
+			return nil, fmt.Errorf("--escalate: %w", err)
+		}
+		step := killStep{sig: sig, name: sigName}
+		if hasWait {
+			if step.wait, err = time.ParseDuration(wait); err != nil || step.wait <= 0 {
+				return nil, fmt.Errorf("--escalate: invalid wait %q after %s", wait, name)
+			}
+		} else if i < len(parts)-1 {
+			return nil, fmt.Errorf("--escalate: %s needs a time to wait before the next signal, e.g. %s:5s", name, name)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// describeSteps names the signals of steps, e.g. "SIGTERM, then SIGKILL
+// after 5s"
+func describeSteps(steps []killStep) string {
+	var text strings.Builder
+	for i, step := range steps {
+		if i > 0 {
+			fmt.Fprintf(&text, ", then %s after %s", step.name, steps[i-1].wait)
+		} else {
+			text.WriteString(step.name)
+		}
+	}
+	return text.String()
+}
+
+// killSubtrees sends the signals of steps to targets, children before
+// their parents. After a step with a wait, the next signal only goes to
+// the processes still running. With report, what became of each process
+// is printed, parents first
+func (t *Tree) killSubtrees(targets []int, steps []killStep, report bool) error {
+	pending := slices.Clone(targets)
+	slices.Reverse(pending)
+	outcomes := map[int]string{}
+	exited, failed := 0, 0
+
+	for n, step := range steps {
+		// the confirmation or the previous wait may have taken long
+		running, gone, err := t.stillRunning(pending)
+		if err != nil {
+			return err
+		}
+		for _, idx := range gone {
+			exited++
+			outcomes[idx] = "already exited"
+			if n > 0 {
+				outcomes[idx] = "exited after " + steps[n-1].name
+			}
+		}
+
+		var sent []int
+		for _, idx := range running {
+			process := t.procs[idx]
+			err := signalProcess(process.PID, step.sig)
+			switch {
+			case errors.Is(err, os.ErrProcessDone):
+				exited++
+				outcomes[idx] = "already exited"
+				if n > 0 {
+					outcomes[idx] = "exited after " + steps[n-1].name
+				}
+			case err != nil:
+				failed++
+				outcomes[idx] = step.name + " failed: " + err.Error()
+				log.Errorf("%d %s: %v", process.PID, process.Cmd, err)
+			default:
+				sent = append(sent, idx)
+				outcomes[idx] = "sent " + step.name
+				log.Debugf("sent %s to %d %s", step.name, process.PID, process.Cmd)
+			}
+		}
+		pending = sent
+		if step.wait > 0 {
+			pending = t.waitExited(slices.Clone(sent), step.wait)
+			running := map[int]bool{}
+			for _, idx := range pending {
+				running[idx] = true
+			}
+			for _, idx := range sent {
+				if !running[idx] {
+					exited++
+					outcomes[idx] = "exited after " + step.name
+				}
+			}
+		}
+	}
+
+	// the survivors of a last step with a wait
+	last := steps[len(steps)-1]
+	sent, running := len(pending), 0
+	if last.wait > 0 {
+		sent, running = 0, len(pending)
+		for _, idx := range pending {
+			outcomes[idx] = "still running after " + last.name
+		}
+	}
+
+	if report {
+		for _, idx := range targets {
+			fmt.Fprintf(t.output, "%d %s: %s\n", t.procs[idx].PID, processName(t.procs[idx]), outcomes[idx])
+		}
+	}
+	summary := []string{fmt.Sprintf("%d exited", exited)}
+	if sent > 0 || last.wait == 0 {
+		summary = append([]string{fmt.Sprintf("%d sent %s", sent, last.name)}, summary...)
+	}
+	if running > 0 {
+		summary = append(summary, fmt.Sprintf("%d still running", running))
+	}
+	summary = append(summary, fmt.Sprintf("%d failed", failed))
+	fmt.Fprintf(t.output, "%d processes: %s\n", len(targets), strings.Join(summary, ", "))
+
+	if failed+running > 0 {
+		return fmt.Errorf("failed to stop %d of %d processes", failed+running, len(targets))
+	}
+	return nil
+}
+
+// waitExited waits up to timeout for the processes to exit, and returns
+// those still running
+func (t *Tree) waitExited(indices []int, timeout time.Duration) []int {
+	deadline := time.Now().Add(timeout)
+	for {
+		indices = slices.DeleteFunc(indices, func(idx int) bool { return !processAlive(t.procs[idx].PID) })
+		if len(indices) == 0 || !time.Now().Before(deadline) {
+			return indices
+		}
+		time.Sleep(min(100*time.Millisecond, time.Until(deadline)))
+	}
+}
+
+// resolveSubtrees snapshots the process table and returns the processes
+// given by pid or matching a pattern, leaving out those below another
+// one, and their subtrees, parents first, and the pids given that are not
+// in the process table. A number is always a pid, never a pattern, a
+// stale pid must not pick up whatever has it in its command line. With
+// spareSelf, pstree and its ancestors are left out, signaling them would
+// stop pstree midway
+func (t *Tree) resolveSubtrees(args []string, spareSelf bool) (roots, targets, missing []int, err error) {
+	if err := t.loadProcesses(); err != nil {
+		return nil, nil, nil, err
+	}
+	t.makeTreeHierarchy()
+
+	var pids []int
+	t.config.SearchStrs = nil
+	for _, arg := range args {
+		pid, err := strconv.Atoi(arg)
+		switch {
+		case err != nil:
+			t.config.SearchStrs = append(t.config.SearchStrs, arg)
+		case t.getPidIndex(pid) == -1:
+			missing = append(missing, pid)
+		default:
+			pids = append(pids, pid)
+		}
+	}
+	if err := t.compileSearch(); err != nil {
+		return nil, nil, nil, &usageError{err}
+	}
+
+	self := map[int]bool{}
+	for idx := t.getPidIndex(myPID); idx != -1; idx = t.procs[idx].ParentIdx {
+		self[idx] = true
+	}
+
+	matched := map[int]bool{}
+	for i, process := range t.procs {
+		// our own command line holds the patterns
+		if process.Group || process.Thread || process.PID == myPID {
+			continue
+		}
+		if !slices.Contains(pids, process.PID) && !(len(t.searchPatterns) > 0 && t.matchesSearch(t.matchText(process))) {
+			continue
+		}
+		if spareSelf && self[i] {
+			log.Warnf("skipping %d %s, pstree runs below it", process.PID, processName(process))
+			continue
+		}
+		matched[i] = true
+	}
+
+	for i := range t.procs {
+		if !matched[i] {
+			continue
+		}
+		below := false
+		for parent := t.procs[i].ParentIdx; parent != -1 && !below; parent = t.procs[parent].ParentIdx {
+			below = matched[parent]
+		}
+		if !below {
+			roots = append(roots, i)
+			targets = append(targets, t.subtreeIndices(i)...)
+		}
+	}
+	return roots, targets, missing, nil
+}
+
+// warnMissing reports the pids given that are not running
+func warnMissing(missing []int) {
+	for _, pid := range missing {
+		fmt.Fprintf(os.Stderr, "pstree: %d: no such process\n", pid)
+	}
+}
+
+// printSubtrees prints the trees below roots, with their pids
+func (t *Tree) printSubtrees(roots []int) {
+	for i := range t.procs {
+		t.procs[i].Print = false
+	}
+	for _, root := range roots {
+		for _, idx := range t.subtreeIndices(root) {
+			t.procs[idx].Print = true
+		}
+	}
+	t.config.POption = true
+	t.config.MaxLDepth = len(t.procs) + 1
+	t.CalculateTerminalWidth()
+
+	renderer := &TextRenderer{t: t}
+	renderer.Begin(roots)
+	for _, root := range roots {
+		t.walkTree(root, renderer.Node)
+	}
+	renderer.End()
+}
+
+// stillRunning splits processes of the snapshot into those still
+// running and those gone since, by a fresh read of the process table. A
+// pid now held by a process started at another time was reused, that
+// process is left alone
+func (t *Tree) stillRunning(indices []int) (running, gone []int, err error) {
+	source, err := t.openSource(t.config.Source)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := source.Processes()
+	if err != nil {
+		return nil, nil, err
+	}
+	started := make(map[int]uint64, len(list))
+	for _, process := range list {
+		started[process.PID] = process.StartTime
+	}
+
+	for _, idx := range indices {
+		process := t.procs[idx]
+		start, ok := started[process.PID]
+		switch {
+		case !ok:
+			gone = append(gone, idx)
+		case start != process.StartTime:
+			log.Warnf("skipping %d %s, its pid now belongs to another process", process.PID, processName(process))
+			gone = append(gone, idx)
+		default:
+			running = append(running, idx)
+		}
+	}
+	return running, gone, nil
+}
+
+// signalProcess sends sig to a process, os.ErrProcessDone tells it had
+// already exited
+func signalProcess(pid int, sig os.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer process.Release()
+	return process.Signal(sig)
+}
+
+// confirm asks a yes or no question on the terminal, no is the default
+func confirm(question string) (bool, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return false, fmt.Errorf("no terminal to confirm on, use --yes")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,43 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// deferCmdlines reports whether the command lines are left out of the
+// snapshot with --low-memory, only the printed processes get theirs once
+// the tree is pruned. They are read upfront when patterns or --where
+// need them to select processes
+func (t *Tree) deferCmdlines() bool {
+	if !t.config.LowMemory || resolveSourceName(t.config.Source) != "proc" || t.whereCmd {
+		return false
+	}
+	patterns := len(t.config.SearchStrs) > 0 || len(t.config.Excludes) > 0 || len(t.config.Highlights) > 0
+	return !patterns || t.config.MatchField != "cmdline"
+}
+
+// readCmdline reads the command line of a process, "" for kernel threads
+// and processes that exited
+func readCmdline(procDir string) string {
+	data, err := os.ReadFile(filepath.Join(procDir, "cmdline"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
+
+// loadCmdlines reads the command lines of the processes left to print
+func (t *Tree) loadCmdlines() {
+	for i := range t.procs {
+		process := &t.procs[i]
+		if !process.Print || process.Group || process.Thread || process.Kernel {
+			continue
+		}
+		if cmdline := readCmdline(filepath.Join("/proc", strconv.Itoa(process.PID))); cmdline != "" {
+			process.Cmd = cmdline
+		}
+	}
+}
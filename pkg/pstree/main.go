@@ -0,0 +1,566 @@
+package pstree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// that's mypid
+	myPID int
+
+	// and my parent pid
+	myPPID int
+
+	// what pid is the rendering starting from
+	//startPID int
+)
+
+func init() {
+
+	myPID = os.Getpid()
+	myPPID = os.Getppid()
+
+}
+
+// Main runs the pstree command line and exits, it is all the main
+// package of the binary does
+func Main() {
+
+	t := newTree()
+	var rootCmd = &cobra.Command{
+		Use:   "pstree [flags] [pid ...]",
+		Short: "Display running processes as a tree",
+		Long: `pstree shows running processes as a tree. The tree is rooted at either pid or init if pid is omitted.
+If a user name is specified, all process trees rooted at processes owned by that user are shown.`,
+		Version: version,
+		Args:    cobra.ArbitraryArgs,
+		// pids, search patterns are left to type
+		ValidArgsFunction: completePids,
+		// errors are reported by main, which also picks the exit status
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := t.setupConfig(args); err != nil {
+				return &usageError{err}
+			}
+			// -d raised the log level
+			log.Debug("main()")
+			// the hooks run between refreshes
+			if t.config.FollowPid == -1 && (t.config.OnNew != "" || t.config.OnExit != "") {
+				return &usageError{fmt.Errorf("--on-new and --on-exit need --follow or pstree watch")}
+			}
+			switch t.config.Output {
+			case "text", "json", "dot":
+			case "gantt", "gantt-svg":
+				return t.showGantt()
+			default:
+				return &usageError{fmt.Errorf("unknown output format %q", t.config.Output)}
+			}
+			if t.config.FollowPid != -1 {
+				return t.watchTree(args)
+			}
+			return t.showTree(args)
+		},
+	}
+
+	t.addTreeFlags(rootCmd)
+	t.addWatchFlags(rootCmd)
+	rootCmd.Flags().StringVar(&t.config.Output, "output", "text", "output format: text, json, dot, gantt or gantt-svg")
+	rootCmd.Flags().StringVar(&t.config.History, "history", "", "recorded history file for the gantt outputs (- for stdin)")
+
+	rootCmd.AddCommand(t.newReniceCmd())
+	rootCmd.AddCommand(t.newKillCmd())
+	rootCmd.AddCommand(t.newPauseCmd(false))
+	rootCmd.AddCommand(t.newPauseCmd(true))
+	rootCmd.AddCommand(t.newFreezeCmd(false))
+	rootCmd.AddCommand(t.newFreezeCmd(true))
+	rootCmd.AddCommand(t.newWaitCmd())
+	rootCmd.AddCommand(t.newExecCmd())
+	rootCmd.AddCommand(t.newWatchCmd())
+	rootCmd.AddCommand(t.newEventsCmd())
+	rootCmd.AddCommand(t.newRecordCmd())
+	rootCmd.AddCommand(t.newReplayCmd())
+	rootCmd.AddCommand(t.newQuotaCmd())
+	rootCmd.AddCommand(t.newBenchCmd())
+	rootCmd.AddCommand(newGenManCmd())
+	rootCmd.AddCommand(newGenDocsCmd())
+
+	// subcommands inherit it
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err}
+	})
+
+	var configPath string
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "read flag defaults from this file instead of "+defaultConfigPath())
+	rootCmd.PersistentFlags().StringVar(&t.config.Color, "color", "auto", "color the output: auto on a terminal unless NO_COLOR is set, always or never")
+	rootCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+
+	// profiles of slow runs to attach to bug reports
+	rootCmd.PersistentFlags().StringVar(&cpuProfile, "cpuprofile", "", "write a pprof cpu profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfile, "memprofile", "", "write a pprof heap profile to this file on exit")
+	rootCmd.PersistentFlags().MarkHidden("cpuprofile")
+	rootCmd.PersistentFlags().MarkHidden("memprofile")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyConfigFile(cmd, configPath); err != nil {
+			return &usageError{err}
+		}
+		if err := setColorMode(t.config.Color); err != nil {
+			return &usageError{err}
+		}
+		return startProfiling()
+	}
+
+	err := rootCmd.Execute()
+	stopProfiling()
+	if err != nil {
+		var status *exitStatusError
+		if errors.As(err, &status) {
+			os.Exit(status.Code)
+		}
+		log.Errorf("Error: %v", err)
+		var usage *usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// exitStatusError makes pstree exit with a specific status, without
+// being reported as an error
+type exitStatusError struct {
+	Code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// usageError is an invalid command line, pstree exits with status 2
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// setupConfig validates the command line and derives the search settings
+func (t *Tree) setupConfig(args []string) error {
+
+	if t.config.DOption {
+		log.SetLevel(log.DebugLevel)
+		log.Debugf("H1")
+	}
+
+	// every argument is a pid to start from or a string to search for
+	t.config.SearchPids = nil
+	t.config.SearchStrs = nil
+	for _, arg := range args {
+		if c, err := strconv.Atoi(arg); err == nil {
+			t.config.SearchPids = append(t.config.SearchPids, c)
+		} else {
+			log.Debugf("search string = %s", arg)
+			t.config.SearchStrs = append(t.config.SearchStrs, arg)
+		}
+	}
+
+	// and more pids may come from files
+	for _, path := range append(t.config.PidsFrom, t.config.PidFiles...) {
+		pids, err := readPidFile(path)
+		if err != nil {
+			return err
+		}
+		t.config.SearchPids = append(t.config.SearchPids, pids...)
+	}
+
+	if t.config.ShowParents != -1 {
+		// the tree starts at the top, down to that pid
+		if len(args) > 0 {
+			return fmt.Errorf("--show-parents doesn't take pid or string arguments")
+		}
+	} else if len(args) == 0 && len(t.config.PidsFrom)+len(t.config.PidFiles) == 0 {
+		// default top pid to the parent pid
+		t.config.SearchPids = []int{myPPID}
+	}
+	log.Debugf("config.SearchPids = %v", t.config.SearchPids)
+
+	if err := t.setupGraphics(); err != nil {
+		return err
+	}
+
+	if t.config.ProbeGlyphs && t.config.Graphics != GraphicsASCII && term.IsTerminal(os.Stdout.Fd()) {
+		if !t.probeGlyphs() {
+			t.config.Graphics = GraphicsASCII
+			t.config.TreeChar = &treeChars[GraphicsASCII]
+		}
+	}
+
+	if err := validateNamespaceType(t.config.Namespace); err != nil {
+		return err
+	}
+
+	if t.config.ShowVSZ || t.config.ShowSwap {
+		t.config.ShowMem = true
+	}
+	if t.config.Cumulative && !t.config.ShowMem {
+		t.config.ShowCPU = true
+	}
+
+	if err := t.setupColumns(); err != nil {
+		return err
+	}
+	if err := t.validateHeat(); err != nil {
+		return err
+	}
+	if err := validateAgeFormat(t.config.AgeFormat); err != nil {
+		return err
+	}
+	if err := validateMatchField(t.config.MatchField); err != nil {
+		return err
+	}
+	if t.config.ByUser && (t.config.Output == "json" || t.config.Output == "dot") {
+		return fmt.Errorf("--by-user only applies to the text output")
+	}
+	if err := t.compileSearch(); err != nil {
+		return err
+	}
+	if err := t.compileExcludes(); err != nil {
+		return err
+	}
+	if err := t.compileHighlights(); err != nil {
+		return err
+	}
+	if err := t.compileWhere(); err != nil {
+		return err
+	}
+
+	if t.config.AOption {
+		t.config.SearchOwners = nil
+		t.config.SearchPids = nil
+	}
+
+	// Validate users if specified
+	var err error
+	if t.searchUsers, err = t.resolveUsers(t.config.SearchOwners); err != nil {
+		return err
+	}
+	if t.excludedUsers, err = t.resolveUsers(t.config.ExcludeOwners); err != nil {
+		return err
+	}
+	if t.config.Group != "" {
+		if t.searchGID, err = resolveGroup(t.config.Group); err != nil {
+			return err
+		}
+	}
+	if t.config.Jobs < 0 {
+		return fmt.Errorf("--jobs must not be negative")
+	}
+	if t.config.Retries < 0 {
+		return fmt.Errorf("--retries must not be negative")
+	}
+	t.minRSS = 0
+	if t.config.MinRSS != "" {
+		if t.minRSS, err = parseSize(t.config.MinRSS); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// showTree takes a fresh snapshot of the process table and renders it
+func (t *Tree) showTree(args []string) error {
+
+	// Get processes
+	if err := t.loadProcessesSampled(); err != nil {
+		return err
+	}
+
+	flush := t.bufferOutput()
+	matched, err := t.renderProcesses(args)
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+	if !matched {
+		// like pgrep, so scripts can test for the processes
+		fmt.Fprintln(os.Stderr, "pstree: no matching processes")
+		return &exitStatusError{Code: 1}
+	}
+	return nil
+}
+
+// renderProcesses renders the tree of the processes currently loaded,
+// and reports whether any process was printed
+func (t *Tree) renderProcesses(args []string) (bool, error) {
+
+	log.Debugf("nProcs = %d", t.nProc)
+
+	if t.nProc == 0 {
+		log.Errorf("no processes read")
+		return false, nil
+	}
+
+	// if we are filtering on pids, ensure the pids exist.
+	// otherwise, if not found, they are strings
+	if len(args) > 0 {
+		found := t.config.SearchPids[:0]
+		for _, pid := range t.config.SearchPids {
+			// pids read from files are skipped when gone
+			if t.getPidIndex(pid) != -1 || !slices.Contains(args, strconv.Itoa(pid)) {
+				found = append(found, pid)
+				continue
+			}
+			// pid not found, it's a string search
+			t.config.SearchStrs = append(t.config.SearchStrs, strconv.Itoa(pid))
+		}
+		if len(found) < len(t.config.SearchPids) {
+			t.config.SearchPids = found
+			if err := t.compileSearch(); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	t.CalculateTerminalWidth()
+	printed, err := t.RenderTree()
+	if err != nil {
+		return false, err
+	}
+	return printed && t.searchMatched(), nil
+}
+
+// showGantt charts the process lifetimes of a recorded history
+func (t *Tree) showGantt() error {
+	if t.config.History == "" {
+		return fmt.Errorf("--output %s needs a recorded --history file", t.config.Output)
+	}
+	history, err := readHistory(t.config.History)
+	if err != nil {
+		return err
+	}
+
+	t.CalculateTerminalWidth()
+	flush := t.bufferOutput()
+	if t.config.Output == "gantt-svg" {
+		printGanttSVG(t.output, history)
+	} else {
+		t.printGantt(t.output, history)
+	}
+	return flush()
+}
+
+// bufferOutput buffers the writes to stdout, so a tree of thousands of
+// lines takes a few write calls instead of one per line. The returned
+// function flushes the buffer and restores output
+func (t *Tree) bufferOutput() func() error {
+	buffered := bufio.NewWriterSize(os.Stdout, 64*1024)
+	t.output = buffered
+	return func() error {
+		t.output = os.Stdout
+		return buffered.Flush()
+	}
+}
+
+// addTreeFlags registers the filtering and display flags shared by the
+// commands that render a tree
+func (t *Tree) addTreeFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&t.config.Regex, "regex", "e", false, "match the search argument as a regular expression against command lines")
+	cmd.Flags().BoolVar(&t.config.IgnoreCase, "ignore-case", false, "match the search argument case-insensitively")
+	cmd.Flags().StringArrayVar(&t.config.Excludes, "exclude", nil, "hide processes matching this pattern and their descendants, can be repeated")
+	cmd.Flags().IntVar(&t.config.Context, "context", -1, "show only n levels of ancestors and descendants around the matching processes")
+	cmd.Flags().IntVarP(&t.config.ShowParents, "show-parents", "s", -1, "show only the chain from the top process down to this pid, and its descendants")
+	cmd.Flags().StringArrayVar(&t.config.PidsFrom, "pids-from", nil, "also start from the pids listed in this file, - for stdin, e.g. pgrep output")
+	cmd.Flags().StringArrayVar(&t.config.PidFiles, "pidfile", nil, "also start from the pid in this pidfile, e.g. /run/app.pid")
+	cmd.Flags().BoolVar(&t.config.LowMemory, "low-memory", false, "defer the command lines until the tree is pruned and read only the printed ones, the rest of the process table is still read whole (Linux)")
+	cmd.Flags().IntVar(&t.config.Retries, "retries", 1, "read again the processes whose parent exited during the snapshot, n times (Linux)")
+	cmd.Flags().IntVar(&t.config.Jobs, "jobs", 0, "read /proc with n workers, 0 for one per cpu (Linux)")
+	cmd.Flags().StringVar(&t.config.Source, "source", "auto", "where processes are read from: "+strings.Join(sourceNames(), ", ")+", file:PATH, procfs:DIR or stdin")
+	cmd.Flags().StringArrayVarP(&t.config.SearchOwners, "user", "u", currentUser(), "show only branches containing processes of user, by name or uid, can be repeated")
+	cmd.Flags().StringVarP(&t.config.Group, "group", "G", "", "show only branches containing processes with this primary or supplementary group, by name or gid")
+	cmd.Flags().StringVar(&t.config.TTY, "tty", "", "show only branches containing processes on this terminal, e.g. pts/3 (Linux)")
+	cmd.Flags().IntVar(&t.config.Session, "session", -1, "show only branches containing processes of this session id")
+	cmd.Flags().BoolVar(&t.config.ShowTTY, "show-tty", false, "show the controlling terminal of each process (Linux)")
+	cmd.Flags().Float64Var(&t.config.MinCPU, "min-cpu", 0, "show only branches containing processes using at least this %cpu over --sample")
+	cmd.Flags().StringVar(&t.config.MinRSS, "min-rss", "", "show only branches containing processes with at least this resident memory, e.g. 100M")
+	cmd.Flags().StringArrayVar(&t.config.Highlights, "highlight", nil, "highlight processes matching a pattern and their ancestors, can be repeated")
+	cmd.Flags().IntSliceVarP(&t.config.HighlightPids, "highlight-pid", "H", nil, "highlight a pid and its ancestors, can be repeated")
+	// -h highlights like psmisc, help is left to --help
+	cmd.Flags().BoolVarP(&t.config.HighlightSelf, "highlight-self", "h", false, "highlight pstree itself and its ancestors, i.e. where the shell is")
+	cmd.Flags().Bool("help", false, "help for "+cmd.Name())
+	cmd.Flags().StringVar(&t.config.MatchField, "match-field", "cmdline", "what patterns are matched against: comm, exe or cmdline")
+	cmd.Flags().StringVar(&t.config.Where, "where", "", `show only branches containing processes matching an expression, e.g. 'user == "www-data" && cmd =~ "php" && rss > 200MB'`)
+	cmd.Flags().BoolVar(&t.config.ShowCPU, "cpu", false, "show the cpu usage of each process, sampled over --sample")
+	cmd.Flags().BoolVar(&t.config.ShowMem, "mem", false, "show the resident memory of each process")
+	cmd.Flags().BoolVar(&t.config.ShowVSZ, "vsz", false, "with --mem, also show the virtual size")
+	cmd.Flags().BoolVar(&t.config.ShowSwap, "swap", false, "with --mem, also show the swapped out memory (Linux)")
+	cmd.Flags().StringVar(&t.config.Heat, "heat", "", "with --cpu or --mem, color each line from green to red by its use of the hottest: cpu or mem")
+	cmd.Flags().BoolVar(&t.config.Sched, "sched", false, "show the nice value, priority and scheduling policy (Linux) of each process, real-time ones in red")
+	cmd.Flags().BoolVar(&t.config.OOM, "oom", false, "show the oom_score and oom_score_adj of each process, coloring the likeliest OOM killer victims (Linux)")
+	cmd.Flags().StringArrayVar(&t.config.Env, "env", nil, "append VAR=value to the processes setting the environment variable VAR, can be repeated (Linux)")
+	cmd.Flags().BoolVar(&t.config.Caps, "caps", false, "show the effective capabilities of each process, e.g. (caps cap_net_admin,cap_sys_ptrace) (Linux)")
+	cmd.Flags().BoolVar(&t.config.Audit, "audit", false, "flag the processes running deleted or setuid executables (Linux)")
+	cmd.Flags().BoolVar(&t.config.Cwd, "cwd", false, "show the working directory of each process, and flag the chrooted ones (Linux)")
+	cmd.Flags().BoolVar(&t.config.IO, "io", false, "show the bytes each process read from and wrote to storage, and the rates in watch mode (Linux)")
+	cmd.Flags().BoolVar(&t.config.Net, "net", false, "show the tcp and udp addresses each process listens on, e.g. (:80,:443) (Linux)")
+	cmd.Flags().BoolVar(&t.config.FDs, "fds", false, "show the number of open file descriptors of each process (Linux)")
+	cmd.Flags().IntVar(&t.config.MinFDs, "min-fds", 0, "show only branches containing processes with at least this many open file descriptors (Linux)")
+	cmd.Flags().BoolVar(&t.config.Age, "age", false, "show how long each process has been running")
+	cmd.Flags().StringVar(&t.config.AgeFormat, "age-format", "elapsed", "with --age, show the elapsed time, e.g. 3d4h, or the start time: elapsed or start")
+	cmd.Flags().BoolVar(&t.config.Cumulative, "cumulative", false, "with --cpu or --mem, also show the totals of each subtree")
+	cmd.Flags().DurationVar(&t.config.Sample, "sample", time.Second, "cpu usage sampling interval")
+	cmd.Flags().StringArrayVar(&t.config.ExcludeOwners, "not-user", nil, "hide processes of user, by name or uid, unless they lead to others, can be repeated")
+	cmd.Flags().BoolVar(&t.config.NumericOwners, "numeric-owners", false, "print uids instead of resolving user names")
+	cmd.Flags().BoolVarP(&t.config.UOption, "no-root", "U", false, "don't show branches containing only root processes")
+	cmd.Flags().BoolVarP(&t.config.POption, "show-pids", "p", false, "show process pids, identical siblings are then not compacted")
+	cmd.Flags().BoolVarP(&t.config.NoCompact, "no-compact", "c", false, "don't compact identical sibling leaves into N*[name]")
+	cmd.Flags().IntVarP(&t.config.MaxLDepth, "level", "l", 100, "print tree to n levels deep")
+	cmd.Flags().BoolVarP(&t.config.AOption, "all", "a", false, "show all processes")
+	cmd.Flags().BoolVarP(&t.config.WOption, "wide", "w", false, "wide output, not truncated to window width")
+	cmd.Flags().IntVar(&t.config.Width, "columns", 0, "truncate output to n columns, even when not writing to a terminal")
+	cmd.Flags().BoolVarP(&t.config.DOption, "debug", "d", false, "print debugging info to stderr")
+	cmd.Flags().BoolVar(&t.config.BirthOrder, "show-birth-order", false, "show each child's position by start time under its parent, e.g. #3")
+	cmd.Flags().BoolVar(&t.config.ByUser, "by-user", false, "print a separate forest for the processes of each user")
+	cmd.Flags().BoolVar(&t.config.Summary, "summary", false, "print the totals of the printed processes per user and state, the tree depth and widest fan-out after the tree")
+	cmd.Flags().BoolVar(&t.config.Counts, "counts", false, "append the number of descendants of each process, e.g. (+12), and what --level hides")
+	cmd.Flags().BoolVar(&t.config.ShowCounts, "show-counts", false, "show direct children and total descendants of each process, e.g. (c:3 d:57)")
+	cmd.Flags().BoolVar(&t.config.CPUQuota, "cpu-quota", false, "annotate cgroup subtrees with their cpu quota and cpuset, e.g. [2.0 CPU on 0-3]")
+	cmd.Flags().StringVarP(&t.config.Namespace, "ns", "N", "", "group processes under a node per namespace of this type: "+strings.Join(namespaceTypes, ", ")+" (Linux)")
+	cmd.Flags().StringVar(&t.config.Jail, "jail", "", "show only processes of this jail, by name or id, and their ancestors (FreeBSD)")
+	cmd.Flags().StringVar(&t.config.Zone, "zone", "", "show only processes of this zone, by name or id, and their ancestors (Solaris/illumos)")
+	cmd.Flags().BoolVar(&t.config.GroupJails, "group-jails", false, "show the processes of each jail or zone under a node of their own (FreeBSD, Solaris/illumos)")
+	cmd.Flags().BoolVar(&t.config.ShowUnit, "show-unit", false, "append the systemd service or scope, or the launchd label on macOS, of the processes starting one")
+	cmd.Flags().BoolVar(&t.config.GroupUnits, "group-units", false, "show processes under a node per systemd slice and unit, like systemd-cgls (Linux)")
+	cmd.Flags().BoolVar(&t.config.GroupPods, "group-pods", false, "show the processes of each kubernetes pod under a pod/NAMESPACE/NAME node (Linux)")
+	cmd.Flags().BoolVar(&t.config.GroupContainers, "group-containers", false, "show the processes of each container under a node of their own (Linux)")
+	cmd.Flags().BoolVar(&t.config.ResolveContainers, "resolve-containers", false, "name containers by asking the docker or podman socket instead of showing their short id")
+	cmd.Flags().BoolVar(&t.config.NSPids, "ns-pids", false, "show the pid inside the process's pid namespace too, e.g. 1234/1 (Linux)")
+	cmd.Flags().StringSliceVar(&t.config.States, "states", nil, "show only processes in these states and their ancestors, e.g. Z,T (Linux)")
+	cmd.Flags().BoolVar(&t.config.HideKernel, "hide-kernel", false, "hide kthreadd and the kernel threads below it (Linux)")
+	cmd.Flags().BoolVarP(&t.config.Threads, "threads", "t", false, "show threads as {name} children of their process (Linux)")
+	cmd.Flags().BoolVar(&t.config.HeaderRow, "header-row", false, "print the -o columns, by default pid, owner, threads and rss, aligned under a header row")
+	cmd.Flags().StringSliceVarP(&t.config.Format, "format", "o", nil, "columns shown after the tree, or left of it with --header-row, e.g. pid,user,cpu,rss,stime,cmd")
+	cmd.Flags().BoolVar(&t.config.Legend, "legend", false, "explain the markers used in the tree after it")
+	cmd.Flags().BoolVar(&t.config.CgroupStats, "cgroup-stats", false, "annotate cgroup subtrees with their memory use and cpu pressure, e.g. [mem 1.2G cpu.pressure 0.8%] (Linux)")
+	cmd.Flags().BoolVar(&t.config.ProbeGlyphs, "probe-glyphs", false, "check that the terminal renders the tree graphics, fall back to ASCII if not")
+	t.addGraphicsFlag(cmd)
+
+	cmd.RegisterFlagCompletionFunc("user", completeUsers)
+	cmd.RegisterFlagCompletionFunc("not-user", completeUsers)
+	cmd.RegisterFlagCompletionFunc("show-parents", completePids)
+	cmd.RegisterFlagCompletionFunc("highlight-pid", completePids)
+	cmd.RegisterFlagCompletionFunc("heat", cobra.FixedCompletions([]string{"cpu", "mem"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// RenderTree prints the marked branches, and reports whether any
+// process was printed. It fails when there is no top process to start
+// from
+func (t *Tree) RenderTree() (bool, error) {
+	t.filterTree()
+	if !t.config.NoCompact && !t.config.POption && t.config.Output != "json" {
+		t.compactSiblings()
+	}
+
+	roots, err := t.rootIndices()
+	if err != nil {
+		return false, err
+	}
+	renderer := t.newRenderer()
+	renderer.Begin(roots)
+	if t.config.ByUser {
+		t.printByUser(roots, renderer)
+	} else {
+		for _, rootIdx := range roots {
+			t.walkTree(rootIdx, renderer.Node)
+		}
+	}
+	renderer.End()
+	return slices.ContainsFunc(roots, func(idx int) bool { return t.procs[idx].Print }), nil
+}
+
+// filterTree builds the hierarchy of the loaded processes and marks
+// those to print
+func (t *Tree) filterTree() {
+	t.resetTree()
+	t.makeTreeHierarchy()
+	if t.config.BirthOrder {
+		t.computeBirthOrder()
+	}
+	if t.config.ShowCounts || t.config.Counts {
+		t.countDescendants()
+	}
+	if t.config.Cumulative {
+		t.accumulateSubtrees()
+	}
+	if t.config.OOM {
+		t.rankOOM()
+	}
+	t.debugPrintProcs(false)
+	t.markProcs()
+	t.narrowMarked()
+	t.excludeProcs()
+	t.excludeUsers()
+	t.dropProcs()
+	if t.deferCmdlines() {
+		t.loadCmdlines()
+	}
+	t.markHighlights()
+	if t.config.Heat != "" {
+		t.rankHeat()
+	}
+	//debugPrintProcs(true)
+}
+
+func isUnicodeTerminal() int {
+	// Check LANG and LC_CTYPE environment variables
+	keys := []string{"LC_ALL", "LC_CTYPE", "LANG"}
+	for _, key := range keys {
+		val := os.Getenv(key)
+		if strings.Contains(strings.ToUpper(val), "UTF-8") {
+			// UTF
+			return GraphicsUTF8
+		}
+	}
+	// ASCII
+	return GraphicsASCII
+}
+
+// currentUsername is looked up once for all the commands taking -u
+var currentUsername = sync.OnceValue(getCurrentUsername)
+
+// currentUser is the default of -u, nothing when it's unknown
+func currentUser() []string {
+	if name := currentUsername(); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+func getCurrentUsername() string {
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	log.Debugf("getCurrentUsername %s", usr.Username)
+	return usr.Username
+}
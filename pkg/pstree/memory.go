@@ -0,0 +1,84 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readStatm reads the virtual and resident sizes of a process, in bytes,
+// from /proc/PID/statm
+func readStatm(procDir string) (vsz, rss uint64, ok bool) {
+	data, err := os.ReadFile(filepath.Join(procDir, "statm"))
+	if err != nil {
+		return 0, 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, 0, false
+	}
+	size, err1 := strconv.ParseUint(fields[0], 10, 64)
+	resident, err2 := strconv.ParseUint(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	pageSize := uint64(os.Getpagesize())
+	return size * pageSize, resident * pageSize, true
+}
+
+// countFDs counts the open file descriptors of a process, -1 when they
+// can't be listed, e.g. for the processes of other users
+func countFDs(procDir string) int {
+	entries, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// fdCount formats the descriptor count of a process, "?" when unknown
+func fdCount(process Process) string {
+	if process.FDs < 0 {
+		return "?"
+	}
+	return strconv.Itoa(process.FDs)
+}
+
+// statusBytes reads a "Key:   123 kB" line of /proc/PID/status
+func statusBytes(status, key string) uint64 {
+	for _, line := range strings.Split(status, "\n") {
+		value, found := strings.CutPrefix(line, key+":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0
+		}
+		kb, _ := strconv.ParseUint(fields[0], 10, 64)
+		return kb * 1024
+	}
+	return 0
+}
+
+// memAnnotation returns the memory use of a process, followed with
+// --cumulative by that of its subtree, e.g. "(rss 12.0M/1.4G vsz 80M/9.1G)"
+func (t *Tree) memAnnotation(process Process) string {
+	field := func(name string, own, subtree uint64) string {
+		if t.config.Cumulative {
+			return fmt.Sprintf("%s %s/%s", name, humanSize(own), humanSize(subtree))
+		}
+		return fmt.Sprintf("%s %s", name, humanSize(own))
+	}
+
+	parts := []string{field("rss", process.RSS, process.SubtreeRSS)}
+	if t.config.ShowVSZ {
+		parts = append(parts, field("vsz", process.VSZ, process.SubtreeVSZ))
+	}
+	if t.config.ShowSwap {
+		parts = append(parts, field("swap", process.Swap, process.SubtreeSwap))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
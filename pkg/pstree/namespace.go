@@ -0,0 +1,43 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// namespaceTypes are the namespaces -N/--ns can group by
+var namespaceTypes = []string{"pid", "net", "mnt", "user", "uts", "ipc"}
+
+// groupByNamespace inserts a TYPE:[inode] node between a process and its
+// children living in another TYPE namespace, so the processes that
+// entered a namespace together sit under its header. Namespaces are only
+// read with the Linux /proc source, the /proc/PID/ns of the host says
+// nothing about a fixture's or ps's processes
+func (t *Tree) groupByNamespace(nsType string) {
+	if resolveSourceName(t.config.Source) != "proc" {
+		return
+	}
+
+	namespaces := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if p.Thread || p.Group {
+			continue
+		}
+		link, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(p.PID), "ns", nsType))
+		if err == nil {
+			namespaces[p.PID] = link
+		}
+	}
+	t.insertGroups(namespaces, func(namespace string) string { return namespace })
+}
+
+// validateNamespaceType checks the argument of -N/--ns
+func validateNamespaceType(nsType string) error {
+	if nsType != "" && !slices.Contains(namespaceTypes, nsType) {
+		return fmt.Errorf("unknown namespace type %q, expected one of %v", nsType, namespaceTypes)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package pstree
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// socketTables are the /proc/net tables of --net, and the state of their
+// listening sockets: LISTEN for tcp, unconnected for udp
+var socketTables = []struct {
+	file, suffix, state string
+}{
+	{"tcp", "", "0A"},
+	{"tcp6", "", "0A"},
+	{"udp", "/udp", "07"},
+	{"udp6", "/udp", "07"},
+}
+
+// readListenSockets parses the tcp, tcp6, udp and udp6 tables of netDir,
+// /proc/net but in tests
+func readListenSockets(netDir string) map[uint64]string {
+	sockets := map[uint64]string{}
+	for _, table := range socketTables {
+		data, err := os.ReadFile(filepath.Join(netDir, table.file))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[min(1, len(lines)):] {
+			// sl local_address rem_address st ... uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != table.state {
+				continue
+			}
+			inode, err := strconv.ParseUint(fields[9], 10, 64)
+			if err != nil || inode == 0 {
+				continue
+			}
+			if address, ok := socketAddress(fields[1]); ok {
+				sockets[inode] = address + table.suffix
+			}
+		}
+	}
+	return sockets
+}
+
+// socketAddress decodes a hex ADDR:PORT of /proc/net, the address in
+// host order 32 bit words. The wildcard address is left out, e.g. ":80"
+func socketAddress(s string) (string, bool) {
+	addrHex, portHex, found := strings.Cut(s, ":")
+	if !found {
+		return "", false
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", false
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || len(raw)%4 != 0 {
+		return "", false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	host := ""
+	if !ip.IsUnspecified() {
+		host = ip.String()
+	}
+	return net.JoinHostPort(host, strconv.FormatUint(port, 10)), true
+}
+
+// listeningPorts returns the addresses a process listens on, sorted
+func (t *Tree) listeningPorts(procDir string) []string {
+	if t.listenSockets == nil {
+		t.listenSockets = readListenSockets("/proc/net")
+	}
+	entries, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return nil
+	}
+	var ports []string
+	for _, entry := range entries {
+		link, err := os.Readlink(filepath.Join(procDir, "fd", entry.Name()))
+		if err != nil {
+			continue
+		}
+		inode, found := strings.CutPrefix(link, "socket:[")
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(inode, "]"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if address, ok := t.listenSockets[n]; ok && !slices.Contains(ports, address) {
+			ports = append(ports, address)
+		}
+	}
+	slices.Sort(ports)
+	return ports
+}
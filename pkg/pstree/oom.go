@@ -0,0 +1,63 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	oomVictimStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9"))
+	oomRiskStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+)
+
+// readOOM reads /proc/PID/oom_score and oom_score_adj
+func readOOM(procDir string) (score, adj int, ok bool) {
+	read := func(name string) (int, error) {
+		data, err := os.ReadFile(filepath.Join(procDir, name))
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	score, err := read("oom_score")
+	if err != nil {
+		return 0, 0, false
+	}
+	adj, _ = read("oom_score_adj")
+	return score, adj, true
+}
+
+// rankOOM finds the highest oom_score, for oomStyle
+func (t *Tree) rankOOM() {
+	t.oomMaxScore = 0
+	for _, process := range t.procs {
+		if !process.Group && !process.Thread {
+			t.oomMaxScore = max(t.oomMaxScore, process.OOMScore)
+		}
+	}
+}
+
+// oomStyle colors the next OOM killer victim, and the processes scoring
+// at least half as high
+func (t *Tree) oomStyle(process Process) (lipgloss.Style, bool) {
+	switch {
+	case t.oomMaxScore == 0 || process.Group || process.Thread:
+		return lipgloss.Style{}, false
+	case process.OOMScore == t.oomMaxScore:
+		return oomVictimStyle, true
+	case process.OOMScore*2 >= t.oomMaxScore:
+		return oomRiskStyle, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// oomAnnotation returns the oom_score and oom_score_adj of a process,
+// e.g. "(oom 667 adj 0)"
+func oomAnnotation(process Process) string {
+	return fmt.Sprintf("(oom %d adj %d)", process.OOMScore, process.OOMScoreAdj)
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package pstree
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid owning a file, for /proc/PID directories
+func fileOwner(info os.FileInfo) (int, bool) {
+	if sysStat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(sysStat.Uid), true
+	}
+	return 0, false
+}
@@ -0,0 +1,8 @@
+package pstree
+
+import "os"
+
+// fileOwner is not meaningful on Windows, where there is no /proc
+func fileOwner(info os.FileInfo) (int, bool) {
+	return 0, false
+}
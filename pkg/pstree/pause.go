@@ -0,0 +1,123 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+// newPauseCmd returns the stop command, or cont when resume is set
+func (t *Tree) newPauseCmd(resume bool) *cobra.Command {
+	use, signal, done := "stop", "STOP", "stopped"
+	short := "Freeze processes and all their descendants with SIGSTOP, deepest first"
+	long := `stop sends SIGSTOP to the subtrees of the pids and of the processes
+matching the patterns, children before their parents, then looks again
+for the processes forked in the meantime until none is left running, so
+a runaway build is frozen as a whole. cont resumes it.`
+	if resume {
+		use, signal, done = "cont", "CONT", "resumed"
+		short = "Resume processes and all their descendants with SIGCONT, parents first"
+		long = `cont sends SIGCONT to the subtrees of the pids and of the processes
+matching the patterns, parents before their children, e.g. to resume
+what stop froze.`
+	}
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:               use + " [--dry-run] pid|pattern ...",
+		Short:             short,
+		Long:              long,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sig, sigName, err := parseSignal(signal)
+			if err != nil {
+				return err
+			}
+			if err := t.setupGraphics(); err != nil {
+				return &usageError{err}
+			}
+
+			roots, targets, missing, err := t.resolveSubtrees(args, true)
+			if err != nil {
+				return err
+			}
+			warnMissing(missing)
+			if len(targets) == 0 {
+				fmt.Fprintln(os.Stderr, "pstree: no matching processes")
+				return &exitStatusError{Code: 1}
+			}
+			if dryRun {
+				t.printSubtrees(roots)
+				fmt.Fprintf(t.output, "%d processes would be sent %s\n", len(targets), sigName)
+				return nil
+			}
+
+			// pids already signaled, or failed, with the result, and
+			// those that exited before their turn
+			signaled := map[int]bool{}
+			exited := map[int]bool{}
+			for {
+				var fresh []int
+				for _, idx := range targets {
+					if _, ok := signaled[t.procs[idx].PID]; !ok && !exited[t.procs[idx].PID] {
+						fresh = append(fresh, idx)
+					}
+				}
+				if len(fresh) == 0 {
+					break
+				}
+				if !resume {
+					slices.Reverse(fresh)
+				}
+				fresh, gone, err := t.stillRunning(fresh)
+				if err != nil {
+					return err
+				}
+				for _, idx := range gone {
+					exited[t.procs[idx].PID] = true
+				}
+				for _, idx := range fresh {
+					process := t.procs[idx]
+					err := signalProcess(process.PID, sig)
+					if err != nil {
+						log.Errorf("%d %s: %v", process.PID, process.Cmd, err)
+					} else {
+						log.Debugf("sent %s to %d %s", sigName, process.PID, process.Cmd)
+					}
+					signaled[process.PID] = err == nil
+				}
+				// resumed processes may fork as they please
+				if resume {
+					break
+				}
+				if _, targets, _, err = t.resolveSubtrees(args, true); err != nil {
+					return err
+				}
+			}
+
+			failed := 0
+			for _, ok := range signaled {
+				if !ok {
+					failed++
+				}
+			}
+			fmt.Fprintf(t.output, "%d processes %s, %d failed\n", len(signaled)-failed, done, failed)
+			if failed > 0 {
+				return fmt.Errorf("failed to signal %d of %d processes", failed, len(signaled))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be signaled")
+	cmd.Flags().BoolVarP(&t.config.Regex, "regex", "e", false, "match the patterns as regular expressions against command lines")
+	cmd.Flags().BoolVar(&t.config.IgnoreCase, "ignore-case", false, "match the patterns case-insensitively")
+	cmd.Flags().StringVar(&t.config.MatchField, "match-field", "cmdline", "what patterns are matched against: comm, exe or cmdline")
+	t.addGraphicsFlag(cmd)
+
+	return cmd
+}
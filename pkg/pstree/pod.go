@@ -0,0 +1,92 @@
+package pstree
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// kubelet keeps pod logs in NAMESPACE_NAME_UID directories
+	kubePodLogDir = "/var/log/pods"
+	// and links container logs as POD_NAMESPACE_CONTAINER-ID.log
+	kubeContainerLogDir = "/var/log/containers"
+)
+
+// podUID finds the uid of the kubernetes pod a process runs in from its
+// cgroup paths, /kubepods/burstable/podUID/... with the cgroupfs driver
+// or /kubepods.slice/kubepods-burstable-podUID.slice/... with systemd,
+// where the dashes of the uid become underscores
+func podUID(cgroups map[string]string) string {
+	for _, cgroupPath := range cgroups {
+		if !strings.Contains(cgroupPath, "kubepods") {
+			continue
+		}
+		for _, dir := range strings.Split(cgroupPath, "/") {
+			dir = strings.TrimSuffix(dir, ".slice")
+			if i := strings.LastIndex(dir, "-pod"); i != -1 {
+				dir = dir[i+1:]
+			}
+			if uid, found := strings.CutPrefix(dir, "pod"); found && uid != "" {
+				return strings.ReplaceAll(uid, "_", "-")
+			}
+		}
+	}
+	return ""
+}
+
+// loadKubeNames reads the pod and container names the kubelet encodes in
+// its log directories
+func (t *Tree) loadKubeNames() {
+	if t.kubePods != nil {
+		return
+	}
+	t.kubePods = map[string]string{}
+	t.kubeContainers = map[string]string{}
+
+	if entries, err := os.ReadDir(kubePodLogDir); err == nil {
+		for _, entry := range entries {
+			// NAMESPACE_NAME_UID, names can't contain underscores
+			fields := strings.Split(entry.Name(), "_")
+			if len(fields) == 3 {
+				t.kubePods[fields[2]] = fields[0] + "/" + fields[1]
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(kubeContainerLogDir); err == nil {
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+			fields := strings.Split(name, "_")
+			if len(fields) != 3 || len(fields[2]) <= containerIDLen {
+				continue
+			}
+			container, id := fields[2][:len(fields[2])-containerIDLen-1], fields[2][len(fields[2])-containerIDLen:]
+			if isContainerID(id) {
+				t.kubeContainers[id] = container
+			}
+		}
+	}
+}
+
+// podName returns the pod/NAMESPACE/NAME node label of a pod, or pod/UID
+// when the kubelet logs don't name it
+func (t *Tree) podName(uid string) string {
+	t.loadKubeNames()
+	if name, ok := t.kubePods[uid]; ok {
+		return "pod/" + name
+	}
+	return "pod/" + uid
+}
+
+// groupByPod inserts a pod/NAMESPACE/NAME node above the processes of
+// each kubernetes pod
+func (t *Tree) groupByPod() {
+	pods := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if !p.Thread {
+			pods[p.PID] = p.Pod
+		}
+	}
+	t.insertGroups(pods, t.podName)
+}
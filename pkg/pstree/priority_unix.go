@@ -0,0 +1,10 @@
+//go:build unix
+
+package pstree
+
+import "golang.org/x/sys/unix"
+
+// setNice changes the scheduling priority of a single process
+func setNice(pid int, nice int) error {
+	return unix.Setpriority(unix.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,7 @@
+package pstree
+
+import "fmt"
+
+func setNice(pid int, nice int) error {
+	return fmt.Errorf("nice values are not supported on Windows")
+}
@@ -0,0 +1,27 @@
+//go:build freebsd || netbsd || openbsd
+
+package pstree
+
+import "bytes"
+
+func init() {
+	registerSource("sysctl", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcessesBSD), nil
+	})
+	defaultSource = "sysctl"
+}
+
+// splitArgv splits the NUL separated argv returned by the kern.proc_args
+// family of sysctls
+func splitArgv(buf []byte) []string {
+	buf = bytes.TrimRight(buf, "\x00")
+	if len(buf) == 0 {
+		return nil
+	}
+	args := bytes.Split(buf, []byte{0})
+	argv := make([]string, len(args))
+	for i, arg := range args {
+		argv[i] = string(arg)
+	}
+	return argv
+}
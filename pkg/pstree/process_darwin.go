@@ -0,0 +1,131 @@
+package pstree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	registerSource("sysctl", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcessesDarwin), nil
+	})
+	defaultSource = "sysctl"
+}
+
+// getProcessesDarwin reads the process table with sysctl(KERN_PROC_ALL)
+// and the full argv of each process with KERN_PROCARGS2
+func (t *Tree) getProcessesDarwin() ([]Process, error) {
+	kprocs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Process, 0, len(kprocs))
+
+	var labels map[int]string
+	if t.config.ShowUnit {
+		labels = launchdLabels()
+	}
+
+	for _, kp := range kprocs {
+		var proc Process
+
+		proc.PID = int(kp.Proc.P_pid)
+		proc.PPID = int(kp.Eproc.Ppid)
+		proc.PGID = int(kp.Eproc.Pgid)
+		proc.UID = int(kp.Eproc.Ucred.Uid)
+		proc.GIDs = []int{int(kp.Eproc.Pcred.P_rgid)}
+		for _, gid := range kp.Eproc.Ucred.Groups[:min(int(kp.Eproc.Ucred.Ngroups), len(kp.Eproc.Ucred.Groups))] {
+			proc.GIDs = append(proc.GIDs, int(gid))
+		}
+
+		proc.Owner = t.ownerName(proc.UID)
+		proc.Started = time.Unix(kp.Proc.P_starttime.Unix())
+		proc.Nice = int(kp.Proc.P_nice)
+		proc.Priority = int(kp.Proc.P_priority)
+
+		proc.Comm = unix.ByteSliceToString(kp.Proc.P_comm[:])
+		proc.Cmd = proc.Comm
+		exe, argv := darwinArgs(proc.PID)
+		if len(argv) > 0 {
+			proc.Cmd = strings.Join(argv, " ")
+		}
+		proc.Exe = exe
+
+		proc.Unit = labels[proc.PID]
+
+		proc.ThreadCount = 1
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+
+		list = append(list, proc)
+	}
+
+	return list, nil
+}
+
+// darwinArgs returns the executable path and the argv of a process.
+// KERN_PROCARGS2 holds argc, the executable path, NUL padding, then argv
+// and the environment
+func darwinArgs(pid int) (string, []string) {
+	buf, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil || len(buf) < 4 {
+		return "", nil
+	}
+
+	argc := int(binary.LittleEndian.Uint32(buf))
+	buf = buf[4:]
+
+	// skip the executable path and its padding
+	end := bytes.IndexByte(buf, 0)
+	if end == -1 {
+		return "", nil
+	}
+	exe := string(buf[:end])
+	buf = buf[end:]
+	for len(buf) > 0 && buf[0] == 0 {
+		buf = buf[1:]
+	}
+
+	argv := make([]string, 0, argc)
+	for len(argv) < argc && len(buf) > 0 {
+		end := bytes.IndexByte(buf, 0)
+		if end == -1 {
+			argv = append(argv, string(buf))
+			break
+		}
+		argv = append(argv, string(buf[:end]))
+		buf = buf[end+1:]
+	}
+	return exe, argv
+}
+
+// launchdLabels maps the pids of the running launchd jobs to their
+// labels. "launchctl list" prints "PID<tab>STATUS<tab>LABEL" lines for
+// the jobs of the caller's domain, the system domain when run as root
+func launchdLabels() map[int]string {
+	labels := map[int]string{}
+	out, err := exec.Command("launchctl", "list").Output()
+	if err != nil {
+		return labels
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		// jobs that aren't running have "-" as pid
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			labels[pid] = fields[2]
+		}
+	}
+	return labels
+}
@@ -0,0 +1,143 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// kinfoProc mirrors the head of struct kinfo_proc from <sys/user.h>, up
+// to ki_numthreads. The kernel reports the full size in ki_structsize
+type kinfoProc struct {
+	Structsize int32
+	Layout     int32
+	// ki_args, ki_paddr, ki_addr, ki_tracep, ki_textvp, ki_fd, ki_vmspace, ki_wchan
+	_          [8]uintptr
+	Pid        int32
+	Ppid       int32
+	Pgid       int32
+	Tpgid      int32
+	Sid        int32
+	Tsid       int32
+	Jobc       int16
+	_          int16
+	_          uint32
+	Siglist    [4]uint32
+	Sigmask    [4]uint32
+	Sigignore  [4]uint32
+	Sigcatch   [4]uint32
+	Uid        uint32
+	Ruid       uint32
+	Svuid      uint32
+	Rgid       uint32
+	Svgid      uint32
+	Ngroups    int16
+	_          int16
+	Groups     [16]uint32
+	Size       uintptr
+	Rssize     int
+	Swrss      int
+	Tsize      int
+	Dsize      int
+	Ssize      int
+	Xstat      uint16
+	Acflag     uint16
+	Pctcpu     uint32
+	Estcpu     uint32
+	Slptime    uint32
+	Swtime     uint32
+	Cow        uint32
+	Runtime    uint64
+	Start      unix.Timeval
+	Childtime  unix.Timeval
+	Flag       int
+	Kiflag     int
+	Traceflag  int32
+	Stat       int8
+	Nice       int8
+	Lock       int8
+	Rqindex    int8
+	_          [2]uint8
+	Tdname     [17]byte
+	Wmesg      [9]byte
+	Login      [18]byte
+	Lockname   [9]byte
+	Comm       [20]byte
+	Emul       [17]byte
+	Loginclass [18]byte
+	Moretdname [4]byte
+	_          [46]byte
+	_          [2]int32
+	Tdev       uint64
+	Oncpu      int32
+	Lastcpu    int32
+	Tracer     int32
+	Flag2      int32
+	Fibnum     int32
+	CrFlags    uint32
+	Jid        int32
+	Numthreads int32
+}
+
+// getProcessesBSD reads the process table with sysctl(KERN_PROC_PROC),
+// one entry per process, and the full argv of each with KERN_PROC_ARGS
+func (t *Tree) getProcessesBSD() ([]Process, error) {
+	buf, err := unix.SysctlRaw("kern.proc.proc")
+	if err != nil {
+		return nil, err
+	}
+
+	list := []Process{}
+	pageSize := uint64(os.Getpagesize())
+
+	for len(buf) > 0 {
+		if len(buf) < int(unsafe.Sizeof(kinfoProc{})) {
+			return nil, fmt.Errorf("kern.proc.proc: short kinfo_proc entry")
+		}
+		kp := (*kinfoProc)(unsafe.Pointer(&buf[0]))
+		size := int(kp.Structsize)
+		if size < int(unsafe.Sizeof(kinfoProc{})) || size > len(buf) {
+			return nil, fmt.Errorf("kern.proc.proc: unexpected kinfo_proc size %d", size)
+		}
+
+		var proc Process
+
+		proc.PID = int(kp.Pid)
+		proc.PPID = int(kp.Ppid)
+		proc.PGID = int(kp.Pgid)
+		proc.SID = int(kp.Sid)
+		proc.UID = int(kp.Uid)
+		proc.GIDs = []int{int(kp.Rgid)}
+		for _, gid := range kp.Groups[:min(int(kp.Ngroups), len(kp.Groups))] {
+			proc.GIDs = append(proc.GIDs, int(gid))
+		}
+		proc.Owner = t.ownerName(proc.UID)
+		proc.ThreadCount = int(kp.Numthreads)
+		proc.RSS = uint64(kp.Rssize) * pageSize
+		proc.VSZ = uint64(kp.Size)
+		proc.Jail = int(kp.Jid)
+		proc.Started = time.Unix(kp.Start.Unix())
+		proc.Nice = int(kp.Nice)
+
+		proc.Cmd = unix.ByteSliceToString(kp.Comm[:])
+		if args, err := unix.SysctlRaw("kern.proc.args", proc.PID); err == nil {
+			if argv := splitArgv(args); len(argv) > 0 {
+				proc.Cmd = strings.Join(argv, " ")
+			}
+		}
+
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+
+		list = append(list, proc)
+		buf = buf[size:]
+	}
+
+	return list, nil
+}
@@ -0,0 +1,121 @@
+package pstree
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const kernProcAll = 0 // KERN_PROC_ALL
+
+// kinfoProc mirrors the head of struct kinfo_proc from <sys/sysctl.h>, up
+// to p_vm_rssize. The kernel truncates each entry to the size we ask for
+type kinfoProc struct {
+	// p_forw ... p_ru
+	_          [12]uint64
+	Eflag      int32
+	Exitsig    int32
+	Flag       int32
+	Pid        int32
+	Ppid       int32
+	Sid        int32
+	Pgid       int32
+	Tpgid      int32
+	Uid        uint32
+	Ruid       uint32
+	Gid        uint32
+	Rgid       uint32
+	Groups     [16]uint32
+	Ngroups    int16
+	Jobc       int16
+	Tdev       uint32
+	Estcpu     uint32
+	RtimeSec   uint32
+	RtimeUsec  uint32
+	Cpticks    int32
+	Pctcpu     uint32
+	Swtime     uint32
+	Slptime    uint32
+	Schedflags int32
+	Uticks     uint64
+	Sticks     uint64
+	Iticks     uint64
+	Tracep     uint64
+	Traceflag  int32
+	Holdcnt    int32
+	Siglist    int32
+	Sigmask    uint32
+	Sigignore  uint32
+	Sigcatch   uint32
+	Stat       int8
+	Priority   uint8
+	Usrpri     uint8
+	Nice       uint8
+	Xstat      uint16
+	Acflag     uint16
+	Comm       [24]byte
+	Wmesg      [8]byte
+	Wchan      uint64
+	Login      [32]byte
+	VmRssize   int32
+	VmTsize    int32
+	VmDsize    int32
+	VmSsize    int32
+	Uvalid     int64
+	UstartSec  uint64
+	UstartUsec uint32
+}
+
+// getProcessesBSD reads the process table with sysctl(KERN_PROC).
+// KERN_PROC_ARGS has no name in the sysctl table x/sys/unix resolves
+// against, so the command is the 24 byte p_comm
+func (t *Tree) getProcessesBSD() ([]Process, error) {
+	size := int(unsafe.Sizeof(kinfoProc{}))
+	// the last mib element caps the number of entries returned
+	buf, err := unix.SysctlRaw("kern.proc", kernProcAll, 0, size, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Process, 0, len(buf)/size)
+	pageSize := uint64(os.Getpagesize())
+
+	for ; len(buf) >= size; buf = buf[size:] {
+		kp := (*kinfoProc)(unsafe.Pointer(&buf[0]))
+
+		var proc Process
+
+		proc.PID = int(kp.Pid)
+		proc.PPID = int(kp.Ppid)
+		proc.PGID = int(kp.Pgid)
+		proc.SID = int(kp.Sid)
+		proc.UID = int(kp.Uid)
+		proc.GIDs = []int{int(kp.Rgid)}
+		for _, gid := range kp.Groups[:min(int(kp.Ngroups), len(kp.Groups))] {
+			proc.GIDs = append(proc.GIDs, int(gid))
+		}
+		proc.Owner = t.ownerName(proc.UID)
+		proc.Cmd = unix.ByteSliceToString(kp.Comm[:])
+		proc.RSS = uint64(kp.VmRssize) * pageSize
+		// p_nice is offset by NZERO
+		proc.Nice = int(kp.Nice) - 20
+		proc.Priority = int(kp.Priority)
+		if kp.Uvalid != 0 {
+			proc.Started = time.Unix(int64(kp.UstartSec), int64(kp.UstartUsec)*1000)
+		}
+
+		// kinfo_proc has no thread count, threads are separate entries
+		// only with KERN_PROC_SHOW_THREADS
+		proc.ThreadCount = 1
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+
+		list = append(list, proc)
+	}
+
+	return list, nil
+}
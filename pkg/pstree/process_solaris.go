@@ -0,0 +1,114 @@
+package pstree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	registerSource("psinfo", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcessesSolaris), nil
+	})
+	defaultSource = "psinfo"
+}
+
+// psinfo mirrors the head of the 64-bit psinfo_t from <sys/procfs.h>, up
+// to pr_zoneid
+type psinfo struct {
+	Flag   int32
+	Nlwp   int32
+	Pid    int32
+	Ppid   int32
+	Pgid   int32
+	Sid    int32
+	Uid    uint32
+	Euid   uint32
+	Gid    uint32
+	Egid   uint32
+	Addr   uint64
+	Size   uint64 // Kbytes
+	Rssize uint64 // Kbytes
+	_      uint64
+	Ttydev uint64
+	Pctcpu uint16
+	Pctmem uint16
+	_      [4]byte
+	Start  [2]int64
+	Time   [2]int64
+	Ctime  [2]int64
+	Fname  [16]byte
+	Psargs [80]byte
+	Wstat  int32
+	Argc   int32
+	Argv   uint64
+	Envp   uint64
+	Dmodel byte
+	_      [3]byte
+	Taskid int32
+	Projid int32
+	Nzomb  int32
+	Poolid int32
+	Zoneid int32
+}
+
+// getProcessesSolaris reads the psinfo file of every process in /proc
+func (t *Tree) getProcessesSolaris() ([]Process, error) {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Process, 0, len(dirs))
+
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "psinfo"))
+		if err != nil {
+			// the process exited meanwhile, or isn't ours to read
+			continue
+		}
+		var info psinfo
+		if err := binary.Read(bytes.NewReader(data), binary.NativeEndian, &info); err != nil {
+			continue
+		}
+
+		var proc Process
+
+		proc.PID = int(info.Pid)
+		proc.PPID = int(info.Ppid)
+		proc.PGID = int(info.Pgid)
+		proc.UID = int(info.Uid)
+		proc.Owner = t.ownerName(proc.UID)
+		proc.ThreadCount = int(info.Nlwp)
+		proc.RSS = info.Rssize * 1024
+		proc.VSZ = info.Size * 1024
+		proc.Started = time.Unix(info.Start[0], info.Start[1])
+		proc.Zone = int(info.Zoneid)
+
+		// pr_psargs holds the first 80 bytes of the command line, newer
+		// illumos also has the complete argv in cmdline
+		proc.Cmd = unix.ByteSliceToString(info.Psargs[:])
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+			if args := strings.TrimRight(string(cmdline), "\x00"); args != "" {
+				proc.Cmd = strings.ReplaceAll(args, "\x00", " ")
+			}
+		}
+		if proc.Cmd == "" {
+			proc.Cmd = unix.ByteSliceToString(info.Fname[:])
+		}
+
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+
+		list = append(list, proc)
+	}
+
+	return list, nil
+}
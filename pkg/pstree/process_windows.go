@@ -0,0 +1,114 @@
+package pstree
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func init() {
+	registerSource("toolhelp", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcessesWindows), nil
+	})
+	defaultSource = "toolhelp"
+}
+
+// getProcessesWindows enumerates processes with CreateToolhelp32Snapshot,
+// completing owners and command lines from each process when allowed
+func (t *Tree) getProcessesWindows() ([]Process, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	if err := windows.Process32First(snapshot, &entry); err != nil {
+		return nil, err
+	}
+
+	list := make([]Process, 0)
+	for {
+		var proc Process
+		proc.PID = int(entry.ProcessID)
+		proc.PPID = int(entry.ParentProcessID)
+		proc.PGID = proc.PID
+		proc.ThreadCount = int(entry.Threads)
+		proc.Cmd = windows.UTF16ToString(entry.ExeFile[:])
+		proc.Owner = "?"
+
+		if handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, entry.ProcessID); err == nil {
+			if owner, ok := t.windowsOwner(handle); ok {
+				proc.Owner = owner
+			}
+			if cmdline, ok := windowsCommandLine(handle); ok && cmdline != "" {
+				proc.Cmd = cmdline
+			}
+			var creation, exit, kernel, user windows.Filetime
+			if err := windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err == nil {
+				proc.Started = time.Unix(0, creation.Nanoseconds())
+			}
+			windows.CloseHandle(handle)
+		}
+
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		list = append(list, proc)
+
+		if err := windows.Process32Next(snapshot, &entry); err != nil {
+			if errors.Is(err, syscall.ERROR_NO_MORE_FILES) {
+				break
+			}
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// windowsOwner returns the account owning a process token
+func (t *Tree) windowsOwner(handle windows.Handle) (string, bool) {
+	var token windows.Token
+	if err := windows.OpenProcessToken(handle, windows.TOKEN_QUERY, &token); err != nil {
+		return "", false
+	}
+	defer token.Close()
+
+	tokenUser, err := token.GetTokenUser()
+	if err != nil {
+		return "", false
+	}
+	if t.config.NumericOwners {
+		return tokenUser.User.Sid.String(), true
+	}
+	account, domain, _, err := tokenUser.User.Sid.LookupAccount("")
+	if err != nil {
+		return tokenUser.User.Sid.String(), true
+	}
+	// same DOMAIN\user form as user.Current, which -u defaults to
+	if domain != "" {
+		return domain + `\` + account, true
+	}
+	return account, true
+}
+
+// windowsCommandLine reads the command line of a process with
+// NtQueryInformationProcess(ProcessCommandLineInformation)
+func windowsCommandLine(handle windows.Handle) (string, bool) {
+	var size uint32
+	err := windows.NtQueryInformationProcess(handle, windows.ProcessCommandLineInformation, nil, 0, &size)
+	if err != windows.STATUS_INFO_LENGTH_MISMATCH || size == 0 {
+		return "", false
+	}
+
+	buf := make([]byte, size)
+	if err := windows.NtQueryInformationProcess(handle, windows.ProcessCommandLineInformation, unsafe.Pointer(&buf[0]), size, &size); err != nil {
+		return "", false
+	}
+	return (*windows.NTUnicodeString)(unsafe.Pointer(&buf[0])).String(), true
+}
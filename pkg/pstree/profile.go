@@ -0,0 +1,57 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+var (
+	// files the --cpuprofile and --memprofile pprof profiles go to
+	cpuProfile string
+	memProfile string
+	// the open cpu profile, nil when not profiling
+	cpuProfileFile *os.File
+)
+
+// startProfiling starts the cpu profile of --cpuprofile
+func startProfiling() error {
+	if cpuProfile == "" {
+		return nil
+	}
+	f, err := os.Create(cpuProfile)
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("--cpuprofile: %w", err)
+	}
+	cpuProfileFile = f
+	return nil
+}
+
+// stopProfiling ends the cpu profile and writes the heap profile of
+// --memprofile, errors are reported but don't change the exit status
+func stopProfiling() {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		cpuProfileFile.Close()
+		cpuProfileFile = nil
+	}
+	if memProfile == "" {
+		return
+	}
+	f, err := os.Create(memProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pstree: --memprofile: %v\n", err)
+		return
+	}
+	defer f.Close()
+	// up to date statistics of the allocations
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		fmt.Fprintf(os.Stderr, "pstree: --memprofile: %v\n", err)
+	}
+}
@@ -0,0 +1,196 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// QuotaLimits are the soft limits of one user, zero values are unlimited
+type QuotaLimits struct {
+	Processes int `yaml:"processes"`
+	Threads   int `yaml:"threads"`
+	// bytes with an optional unit, e.g. 4G
+	RSS string `yaml:"rss"`
+	// percent of one cpu ("200%") or a number of cpus ("2")
+	CPU string `yaml:"cpu"`
+}
+
+// QuotaPolicy holds the limits applied to every user, and per user overrides
+type QuotaPolicy struct {
+	Default QuotaLimits            `yaml:"default"`
+	Users   map[string]QuotaLimits `yaml:"users"`
+}
+
+// quotaUsage is what a user consumes, or is allowed to consume
+type quotaUsage struct {
+	Processes int
+	Threads   int
+	RSS       uint64
+	CPU       float64
+}
+
+func (t *Tree) newQuotaCmd() *cobra.Command {
+	var policyFile string
+
+	cmd := &cobra.Command{
+		Use:   "quota --policy FILE",
+		Short: "Summarize per-user resource usage against soft limits",
+		Long: `quota totals processes, threads, resident memory and cpu usage per user,
+compares them with the soft limits of a YAML policy and prints the trees
+of the users over their limits. It exits with status 1 when any user is
+over a limit.
+
+  default:
+    processes: 200
+    threads: 2000
+    rss: 4G
+    cpu: 200%
+  users:
+    build:
+      cpu: 800%`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(policyFile)
+			if err != nil {
+				return err
+			}
+			var policy QuotaPolicy
+			if err := yaml.Unmarshal(data, &policy); err != nil {
+				return fmt.Errorf("%s: %w", policyFile, err)
+			}
+
+			if err := t.setupConfig(nil); err != nil {
+				return &usageError{err}
+			}
+			if err := t.sampleCPU(t.config.Sample); err != nil {
+				return err
+			}
+
+			usage := map[string]*quotaUsage{}
+			for _, p := range t.procs {
+				if p.Thread || p.Group {
+					continue
+				}
+				u, ok := usage[p.Owner]
+				if !ok {
+					u = &quotaUsage{}
+					usage[p.Owner] = u
+				}
+				u.Processes++
+				u.Threads += max(p.ThreadCount, 1)
+				u.RSS += p.RSS
+				u.CPU += p.CPUPercent
+			}
+
+			users := make([]string, 0, len(usage))
+			for name := range usage {
+				users = append(users, name)
+			}
+			sort.Strings(users)
+
+			var violators []string
+			violations := map[string][]string{}
+
+			fmt.Fprintf(t.output, "%-16s %8s %8s %8s %8s\n", "USER", "PROCS", "THREADS", "RSS", "%CPU")
+			for _, name := range users {
+				used := usage[name]
+				limit, err := policy.limitsFor(name)
+				if err != nil {
+					return fmt.Errorf("%s: %w", policyFile, err)
+				}
+
+				mark := func(over bool, value, limit string) string {
+					if over {
+						violations[name] = append(violations[name], value+" > "+limit)
+						return value + "!"
+					}
+					return value
+				}
+				procsCol := mark(limit.Processes > 0 && used.Processes > limit.Processes,
+					strconv.Itoa(used.Processes), strconv.Itoa(limit.Processes)+" processes")
+				threadsCol := mark(limit.Threads > 0 && used.Threads > limit.Threads,
+					strconv.Itoa(used.Threads), strconv.Itoa(limit.Threads)+" threads")
+				rssCol := mark(limit.RSS > 0 && used.RSS > limit.RSS,
+					humanSize(used.RSS), humanSize(limit.RSS)+" rss")
+				cpuCol := mark(limit.CPU > 0 && used.CPU > limit.CPU,
+					fmt.Sprintf("%.1f", used.CPU), fmt.Sprintf("%.0f%% cpu", limit.CPU))
+
+				fmt.Fprintf(t.output, "%-16s %8s %8s %8s %8s\n", name, procsCol, threadsCol, rssCol, cpuCol)
+				if len(violations[name]) > 0 {
+					violators = append(violators, name)
+				}
+			}
+
+			if len(violators) == 0 {
+				return nil
+			}
+
+			fmt.Fprintf(t.output, "\n%d of %d users over their limits\n", len(violators), len(users))
+			t.CalculateTerminalWidth()
+			for _, name := range violators {
+				fmt.Fprintf(t.output, "\n%s: %s\n", name, strings.Join(violations[name], ", "))
+				t.searchUsers = []userFilter{{name: name, uid: -1}}
+				t.config.SearchPids = nil
+				t.config.SearchStrs = nil
+				t.config.AOption = false
+				t.config.UOption = false
+				if _, err := t.RenderTree(); err != nil {
+					return err
+				}
+			}
+			return &exitStatusError{Code: 1}
+		},
+	}
+
+	t.addTreeFlags(cmd)
+	cmd.Flags().StringVar(&policyFile, "policy", "", "YAML file with the soft limits")
+	cmd.MarkFlagRequired("policy")
+
+	return cmd
+}
+
+// limitsFor merges the default limits with the overrides of a user
+func (policy QuotaPolicy) limitsFor(name string) (quotaUsage, error) {
+	limits := policy.Default
+	if override, ok := policy.Users[name]; ok {
+		if override.Processes != 0 {
+			limits.Processes = override.Processes
+		}
+		if override.Threads != 0 {
+			limits.Threads = override.Threads
+		}
+		if override.RSS != "" {
+			limits.RSS = override.RSS
+		}
+		if override.CPU != "" {
+			limits.CPU = override.CPU
+		}
+	}
+
+	parsed := quotaUsage{Processes: limits.Processes, Threads: limits.Threads}
+	if limits.RSS != "" {
+		rss, err := parseSize(limits.RSS)
+		if err != nil {
+			return parsed, err
+		}
+		parsed.RSS = rss
+	}
+	if limits.CPU != "" {
+		percent := strings.HasSuffix(limits.CPU, "%")
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(limits.CPU, "%"), 64)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid cpu limit %q", limits.CPU)
+		}
+		if !percent {
+			cpu *= 100
+		}
+		parsed.CPU = cpu
+	}
+	return parsed, nil
+}
@@ -0,0 +1,167 @@
+package pstree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func (t *Tree) newRecordCmd() *cobra.Command {
+	var (
+		file     string
+		interval time.Duration
+		duration time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "record -o FILE [--interval 1s] [--duration 60s]",
+		Short: "Capture periodic snapshots of the process table to a file",
+		Long: `record saves a snapshot of the whole process table every interval, until
+the duration elapses or it is interrupted. Recordings can be played back
+with 'pstree replay' or charted with 'pstree --output gantt --history'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return &usageError{fmt.Errorf("--interval must be positive")}
+			}
+
+			var w io.Writer = os.Stdout
+			if file != "-" {
+				f, err := os.Create(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			count := 0
+			for {
+				if err := t.loadProcesses(); err != nil {
+					return err
+				}
+				if err := writeSnapshot(w, t.currentSnapshot()); err != nil {
+					return err
+				}
+				count++
+				log.Debugf("recorded snapshot %d with %d processes", count, t.nProc)
+
+				select {
+				case <-ctx.Done():
+					fmt.Fprintf(os.Stderr, "recorded %d snapshots\n", count)
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "output", "o", "", "file to record to (- for stdout)")
+	cmd.Flags().DurationVar(&interval, "interval", time.Second, "time between snapshots")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "stop recording after this long (default until interrupted)")
+	cmd.MarkFlagRequired("output")
+
+	return cmd
+}
+
+func (t *Tree) newReplayCmd() *cobra.Command {
+	var (
+		speed float64
+		at    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay FILE [flags] [pid ...]",
+		Short: "Play back a recorded session",
+		Long: `replay redraws the snapshots of a recording in place, with the original
+timing scaled by --speed. --at shows the single snapshot closest to an
+offset into the recording instead. The usual filtering and display
+flags apply to every snapshot.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if speed <= 0 {
+				return &usageError{fmt.Errorf("--speed must be positive")}
+			}
+			file := args[0]
+			history, err := readHistory(file)
+			if err != nil {
+				return err
+			}
+			args = args[1:]
+
+			if err := t.setupConfig(args); err != nil {
+				return &usageError{err}
+			}
+			// the recorder's parent is meaningless here
+			if len(args) == 0 {
+				t.config.SearchPids = nil
+			}
+
+			begin := history[0].Time
+			frame := func(i int) error {
+				header := fmt.Sprintf("Replay %s  %d/%d  %s  +%s", file, i+1, len(history),
+					history[i].Time.Format(time.TimeOnly), history[i].Time.Sub(begin).Round(time.Second))
+				t.useSnapshot(history[i])
+				return t.redraw(header, "", args)
+			}
+
+			if cmd.Flags().Changed("at") {
+				i := 0
+				for i+1 < len(history) && !history[i+1].Time.After(begin.Add(at)) {
+					i++
+				}
+				fmt.Print(clearScreen)
+				return frame(i)
+			}
+
+			if !t.config.DOption {
+				log.SetLevel(log.WarnLevel)
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Print(hideCursor + clearScreen)
+			defer fmt.Print(showCursor)
+
+			for i := range history {
+				if err := frame(i); err != nil {
+					return err
+				}
+				if i+1 == len(history) {
+					break
+				}
+				delay := time.Duration(float64(history[i+1].Time.Sub(history[i].Time)) / speed)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(delay):
+				}
+			}
+			return nil
+		},
+	}
+
+	t.addTreeFlags(cmd)
+	cmd.Flags().Float64Var(&speed, "speed", 1, "playback speed factor")
+	cmd.Flags().DurationVar(&at, "at", 0, "show only the snapshot at this offset into the recording")
+
+	return cmd
+}
@@ -0,0 +1,330 @@
+package pstree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/mattn/go-runewidth"
+)
+
+// TreeNode is a process visited by walkTree
+type TreeNode struct {
+	Process Process
+	// index of the process in the table
+	Idx int
+	// 1 for the roots, 2 for their children and so on
+	Depth int
+	// the tree graphics of the ancestors, "" for the roots
+	Head string
+}
+
+// Renderer formats the trees walkTree visits. Adding an output format
+// is implementing one, the traversal stays the same
+type Renderer interface {
+	// Begin is called before the trees below roots, table indices
+	Begin(roots []int)
+	// Node is called for every printed process, parents before their
+	// children and siblings in order
+	Node(node TreeNode)
+	// End is called after the last tree
+	End()
+}
+
+// newRenderer returns the renderer of the --output format, unless the
+// tree has one set, e.g. the watch screen
+func (t *Tree) newRenderer() Renderer {
+	if t.renderer != nil {
+		return t.renderer
+	}
+	switch t.config.Output {
+	case "json":
+		return &JSONRenderer{t: t}
+	case "dot":
+		return &DOTRenderer{t: t}
+	}
+	return &TextRenderer{t: t}
+}
+
+// treeFrame is a process waiting to be visited by walkTree, with the
+// graphics leading to it and its depth
+type treeFrame struct {
+	idx   int
+	head  string
+	depth int
+}
+
+// walkTree visits the processes below idx, down to --level. It walks
+// the tree with an explicit stack, so thousands of nested levels are no
+// issue
+func (t *Tree) walkTree(idx int, visit func(TreeNode)) {
+	if !t.procs[idx].Print {
+		return
+	}
+
+	base := t.atLDepth
+	stack := []treeFrame{{idx, "", base + 1}}
+	for len(stack) > 0 {
+		frame := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if frame.depth > t.config.MaxLDepth {
+			continue
+		}
+		// nodeLabel tells the processes at the --level limit by it
+		t.atLDepth = frame.depth
+		process := t.procs[frame.idx]
+		visit(TreeNode{Process: process, Idx: frame.idx, Depth: frame.depth - base, Head: frame.head})
+
+		var nhead string
+		if frame.head == "" {
+			nhead = " "
+		} else if process.SisterIdx != -1 {
+			nhead = frame.head + t.config.TreeChar.Bar + " "
+		} else {
+			nhead = frame.head + "  "
+		}
+
+		// children are pushed last first, so they pop in order
+		start := len(stack)
+		for child := process.ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+			stack = append(stack, treeFrame{child, nhead, frame.depth + 1})
+		}
+		slices.Reverse(stack[start:])
+	}
+	t.atLDepth = base
+}
+
+// TextRenderer draws the trees with the --graphics characters, one line
+// per process, followed by the --summary and --legend
+type TextRenderer struct {
+	t     *Tree
+	roots []int
+}
+
+func (r *TextRenderer) Begin(roots []int) {
+	t := r.t
+	r.roots = roots
+	// Print initialization string
+	fmt.Fprint(t.output, t.config.TreeChar.Init)
+	if t.config.HeaderRow {
+		fmt.Fprintln(t.output, t.columnHeader())
+	}
+}
+
+func (r *TextRenderer) Node(node TreeNode) {
+	t := r.t
+	process, head := node.Process, node.Head
+
+	var pgl string
+	if process.PID == process.PGID {
+		pgl = t.config.TreeChar.PGL
+	} else {
+		pgl = t.config.TreeChar.NPGL
+	}
+
+	var barChar string
+	if head == "" {
+		barChar = ""
+	} else if process.SisterIdx != -1 {
+		barChar = t.config.TreeChar.BarC
+	} else {
+		barChar = t.config.TreeChar.BarL
+	}
+
+	var pChar string
+	if process.ChildIdx != -1 {
+		pChar = t.config.TreeChar.P
+	} else {
+		pChar = t.config.TreeChar.S2
+	}
+
+	var cells string
+	if t.config.HeaderRow {
+		cells = t.columnCells(node.Idx)
+		if process.Group {
+			cells = strings.Repeat(" ", len(cells))
+		}
+	}
+
+	graphics := fmt.Sprintf("%s%s%s%s%s%s%s ",
+		cells,
+		t.config.TreeChar.SG,
+		head,
+		barChar,
+		pChar,
+		pgl,
+		t.config.TreeChar.EG)
+	out := graphics + t.nodeLabel(node.Idx)
+
+	out = t.truncateLine(out)
+	fmt.Fprintln(t.output, t.colorLine(node.Idx, out, len(graphics)))
+}
+
+// truncateLine cuts a line to the terminal width, by the columns its
+// characters take, e.g. 2 for CJK ones and none for the VT100 shifts,
+// and ends it with an ellipsis
+func (t *Tree) truncateLine(line string) string {
+	tail := "..."
+	if unicodeGraphics(t.config.Graphics) {
+		tail = "…"
+	}
+	return runewidth.Truncate(line, t.config.Columns-1, tail)
+}
+
+func (r *TextRenderer) End() {
+	if r.t.config.Summary {
+		r.t.printSummary(r.roots)
+	}
+	if r.t.config.Legend {
+		r.t.printLegend()
+	}
+}
+
+// jsonNode is a process of the JSON output, with its children nested
+type jsonNode struct {
+	Process
+	// a synthetic node, e.g. a namespace or the orphans, named by cmd
+	Group    bool        `json:"group,omitempty"`
+	Children []*jsonNode `json:"children,omitempty"`
+}
+
+// JSONRenderer prints the trees as an array of nested processes, with
+// the fields recordings use
+type JSONRenderer struct {
+	t     *Tree
+	roots []*jsonNode
+	// the ancestors of the next node, by depth
+	path []*jsonNode
+}
+
+func (r *JSONRenderer) Begin(roots []int) {
+	r.roots = []*jsonNode{}
+}
+
+func (r *JSONRenderer) Node(node TreeNode) {
+	n := &jsonNode{Process: node.Process, Group: node.Process.Group}
+	r.path = r.path[:min(node.Depth-1, len(r.path))]
+	if len(r.path) == 0 {
+		r.roots = append(r.roots, n)
+	} else {
+		parent := r.path[len(r.path)-1]
+		parent.Children = append(parent.Children, n)
+	}
+	r.path = append(r.path, n)
+}
+
+func (r *JSONRenderer) End() {
+	encoder := json.NewEncoder(r.t.output)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(r.roots)
+}
+
+// DOTRenderer prints the trees as a Graphviz digraph, labeled like the
+// text output, e.g. pstree --output dot | dot -Tsvg > tree.svg
+type DOTRenderer struct {
+	t *Tree
+	// the ancestors of the next node, by depth
+	path []int
+}
+
+func (r *DOTRenderer) Begin(roots []int) {
+	fmt.Fprintln(r.t.output, "digraph pstree {")
+	fmt.Fprintln(r.t.output, "  rankdir=LR;")
+	fmt.Fprintln(r.t.output, "  node [shape=box, fontname=monospace];")
+}
+
+func (r *DOTRenderer) Node(node TreeNode) {
+	t := r.t
+	attrs := ""
+	if node.Process.Highlight == highlightMatch {
+		attrs = ", style=bold"
+	}
+	fmt.Fprintf(t.output, "  n%d [label=%s%s];\n", node.Idx, dotQuote(t.nodeLabel(node.Idx)), attrs)
+
+	r.path = r.path[:min(node.Depth-1, len(r.path))]
+	if len(r.path) > 0 {
+		fmt.Fprintf(t.output, "  n%d -> n%d;\n", r.path[len(r.path)-1], node.Idx)
+	}
+	r.path = append(r.path, node.Idx)
+}
+
+func (r *DOTRenderer) End() {
+	fmt.Fprintln(r.t.output, "}")
+}
+
+// dotQuote quotes a DOT string, the lines of multi-line commands are
+// left aligned
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\l`)
+	return `"` + s + `"`
+}
+
+// TUIRenderer draws the text output full screen, for watch and replay:
+// the frame is rendered off screen, then painted over the previous one
+// from the top left corner, clearing leftovers at the end of every line
+type TUIRenderer struct {
+	text TextRenderer
+	// the first line of the screen, and an optional last one
+	header string
+	footer string
+
+	frame  bytes.Buffer
+	screen io.Writer
+}
+
+func (r *TUIRenderer) Begin(roots []int) {
+	t := r.text.t
+	r.frame.Reset()
+	r.screen = t.output
+	t.output = &r.frame
+	r.text.Begin(roots)
+}
+
+func (r *TUIRenderer) Node(node TreeNode) {
+	r.text.Node(node)
+}
+
+func (r *TUIRenderer) End() {
+	r.text.End()
+	r.text.t.output = r.screen
+
+	lines := strings.Split(strings.TrimRight(r.frame.String(), "\n"), "\n")
+	lines = append([]string{r.header, ""}, lines...)
+
+	var tail []string
+	if r.footer != "" {
+		tail = []string{"", r.footer}
+	}
+
+	// Don't let the terminal scroll, it would break the cursor home redraw
+	if _, rows, err := term.GetSize(os.Stdout.Fd()); err == nil && rows > 0 && len(lines)+len(tail) > rows-1 {
+		lines = lines[:max(rows-1-len(tail), 0)]
+	}
+	lines = append(lines, tail...)
+
+	var screen strings.Builder
+	screen.WriteString(cursorHome)
+	for _, line := range lines {
+		screen.WriteString(line)
+		screen.WriteString(clearLine)
+		screen.WriteString("\n")
+	}
+	screen.WriteString(clearToEnd)
+	io.WriteString(r.screen, screen.String())
+}
+
+// redraw paints the tree of the loaded processes over the screen with
+// a TUIRenderer, under header and above an optional footer
+func (t *Tree) redraw(header, footer string, args []string) error {
+	t.renderer = &TUIRenderer{text: TextRenderer{t: t}, header: header, footer: footer}
+	defer func() { t.renderer = nil }()
+	_, err := t.renderProcesses(args)
+	return err
+}
@@ -0,0 +1,122 @@
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func (t *Tree) newReniceCmd() *cobra.Command {
+	var (
+		pid       int
+		recursive bool
+		nice      int
+		ionice    string
+		dryRun    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "renice [pid|pattern ... | --pid PID [--recursive]] [-n NICE] [--ionice CLASS[:LEVEL]]",
+		Short: "Change the nice value and I/O priority of a process or a whole subtree",
+		Long: `renice changes the nice value and the I/O priority of the whole subtrees
+of the pids and of the processes matching the patterns, or of the --pid
+process, and its descendants with --recursive. What became of each
+process is printed.`,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) > 0) == cmd.Flags().Changed("pid") {
+				return &usageError{fmt.Errorf("give either pid or pattern arguments, or --pid")}
+			}
+
+			setNiceValue := cmd.Flags().Changed("nice")
+			if !setNiceValue && ionice == "" {
+				return fmt.Errorf("nothing to do, use -n and/or --ionice")
+			}
+
+			var prio IOPriority
+			if ionice != "" {
+				var err error
+				if prio, err = parseIOPriority(ionice); err != nil {
+					return err
+				}
+			}
+
+			var targets, missing []int
+			var err error
+			if len(args) > 0 {
+				_, targets, missing, err = t.resolveSubtrees(args, false)
+			} else {
+				targets, err = t.resolveTargets(pid, recursive)
+			}
+			if err != nil {
+				return err
+			}
+			warnMissing(missing)
+			if len(targets) == 0 {
+				fmt.Fprintln(os.Stderr, "pstree: no matching processes")
+				return &exitStatusError{Code: 1}
+			}
+
+			changed, failed := 0, 0
+			for _, idx := range targets {
+				process := t.procs[idx]
+
+				var actions []string
+				if setNiceValue {
+					actions = append(actions, fmt.Sprintf("nice %d", nice))
+				}
+				if ionice != "" {
+					actions = append(actions, fmt.Sprintf("ionice %s", prio))
+				}
+
+				if dryRun {
+					fmt.Fprintf(t.output, "would set %s on %d %s\n", strings.Join(actions, ", "), process.PID, process.Cmd)
+					continue
+				}
+
+				var errs []error
+				if setNiceValue {
+					if err := setNice(process.PID, nice); err != nil {
+						errs = append(errs, fmt.Errorf("nice: %w", err))
+					}
+				}
+				if ionice != "" {
+					if err := setIOPriority(process.PID, prio); err != nil {
+						errs = append(errs, fmt.Errorf("ionice: %w", err))
+					}
+				}
+
+				if err := errors.Join(errs...); err != nil {
+					failed++
+					fmt.Fprintf(t.output, "%d %s: failed, %s\n", process.PID, processName(process), strings.ReplaceAll(err.Error(), "\n", ", "))
+				} else {
+					changed++
+					fmt.Fprintf(t.output, "%d %s: %s\n", process.PID, processName(process), strings.Join(actions, ", "))
+				}
+			}
+
+			if dryRun {
+				fmt.Fprintf(t.output, "%d processes would be changed\n", len(targets))
+				return nil
+			}
+			fmt.Fprintf(t.output, "%d processes changed, %d failed\n", changed, failed)
+			if failed > 0 {
+				return fmt.Errorf("failed to change %d of %d processes", failed, len(targets))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&pid, "pid", -1, "process to change, instead of the subtrees of the arguments")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "also apply to all descendants of the process")
+	cmd.Flags().IntVarP(&nice, "nice", "n", 0, "nice value to set (-20..19)")
+	cmd.Flags().StringVar(&ionice, "ionice", "", "I/O class to set: none, realtime[:0-7], best-effort[:0-7] or idle")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be changed")
+	cmd.RegisterFlagCompletionFunc("pid", completePids)
+
+	return cmd
+}
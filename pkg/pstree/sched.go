@@ -0,0 +1,44 @@
+package pstree
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// schedPolicies names the Linux scheduling policies of /proc/PID/stat,
+// like chrt does
+var schedPolicies = map[int]string{
+	0: "OTHER",
+	1: "FIFO",
+	2: "RR",
+	3: "BATCH",
+	5: "IDLE",
+	6: "DEADLINE",
+}
+
+// realTimeStyle marks the real-time processes with --sched
+var realTimeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+// isRealTime reports whether a process runs under a real-time policy
+func isRealTime(process Process) bool {
+	switch process.Policy {
+	case "FIFO", "RR", "DEADLINE":
+		return true
+	}
+	return false
+}
+
+// schedAnnotation returns the scheduling of a process, e.g.
+// "(OTHER nice 0 pri 20)" or "(FIFO rtprio 50)". The policy is only
+// known on Linux
+func schedAnnotation(process Process) string {
+	if isRealTime(process) {
+		return fmt.Sprintf("(%s rtprio %d)", process.Policy, process.RTPriority)
+	}
+	label := fmt.Sprintf("nice %d pri %d", process.Nice, process.Priority)
+	if process.Policy != "" {
+		label = process.Policy + " " + label
+	}
+	return "(" + label + ")"
+}
@@ -0,0 +1,129 @@
+package pstree
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// compilePatterns compiles search or exclude patterns: substrings by
+// default, regexps with -e/--regex, case-insensitive with --ignore-case
+func (t *Tree) compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		expr := pattern
+		if !t.config.Regex {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if t.config.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// compileSearch prepares the search arguments for matchesSearch
+func (t *Tree) compileSearch() error {
+	var err error
+	t.searchPatterns, err = t.compilePatterns(t.config.SearchStrs)
+	return err
+}
+
+// compileExcludes prepares the --exclude patterns for excludeProcs
+func (t *Tree) compileExcludes() error {
+	var err error
+	t.excludePatterns, err = t.compilePatterns(t.config.Excludes)
+	return err
+}
+
+// excludeProcs unmarks the processes matching an --exclude pattern and
+// all their descendants
+func (t *Tree) excludeProcs() {
+	for i := range t.procs {
+		// our own command line holds the patterns
+		if !t.procs[i].Print || t.procs[i].Group || t.procs[i].PID == myPID {
+			continue
+		}
+		if matchesAny(t.excludePatterns, t.matchText(t.procs[i])) {
+			for _, idx := range t.subtreeIndices(i) {
+				t.procs[idx].Print = false
+			}
+		}
+	}
+}
+
+// matchFields are the values of --match-field
+var matchFields = []string{"comm", "exe", "cmdline"}
+
+// validateMatchField checks the --match-field argument
+func validateMatchField(field string) error {
+	if !slices.Contains(matchFields, field) {
+		return fmt.Errorf("invalid --match-field %q, expected one of %s", field, strings.Join(matchFields, ", "))
+	}
+	return nil
+}
+
+// matchText returns what search and exclude patterns are compared
+// against: the command name, the executable path, or the command line.
+// Sources without them fall back to the name or path in argv[0]
+func (t *Tree) matchText(process Process) string {
+	switch t.config.MatchField {
+	case "comm":
+		if process.Comm != "" {
+			return process.Comm
+		}
+		return commandName(process.Cmd)
+	case "exe":
+		if process.Exe != "" {
+			return process.Exe
+		}
+		if fields := strings.Fields(process.Cmd); len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+	return process.Cmd
+}
+
+// matchesSearch reports whether a command line matches one of the search
+// arguments
+func (t *Tree) matchesSearch(cmd string) bool {
+	return matchesAny(t.searchPatterns, cmd)
+}
+
+// searchMatched reports whether a searched pid or pattern matches a
+// process, or nothing was searched
+func (t *Tree) searchMatched() bool {
+	if len(t.config.SearchPids) == 0 && len(t.config.SearchStrs) == 0 {
+		return true
+	}
+	for i := range t.procs {
+		process := t.procs[i]
+		if process.Group {
+			continue
+		}
+		if slices.Contains(t.config.SearchPids, process.PID) {
+			return true
+		}
+		if len(t.config.SearchStrs) > 0 && process.PID != myPID && t.matchesSearch(t.matchText(process)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether s matches one of the patterns
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package pstree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// defaultKillSignal is what kill sends without --signal, letting the
+// processes clean up
+const defaultKillSignal = "TERM"
+
+// parseSignal parses a signal name, with or without SIG, or number, and
+// returns it with its canonical name, e.g. SIGTERM
+func parseSignal(spec string) (os.Signal, string, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if name := unix.SignalName(syscall.Signal(n)); name != "" {
+			return syscall.Signal(n), name, nil
+		}
+		return nil, "", fmt.Errorf("unknown signal %d", n)
+	}
+	name := strings.ToUpper(spec)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, name, nil
+	}
+	return nil, "", fmt.Errorf("unknown signal %q", spec)
+}
+
+// processAlive reports whether a process is still running, zombies
+// waiting for their parent to reap them have exited
+func processAlive(pid int) bool {
+	if err := unix.Kill(pid, 0); errors.Is(err, unix.ESRCH) {
+		return false
+	}
+	_, zombie := zombieExitStatus(pid)
+	return !zombie
+}
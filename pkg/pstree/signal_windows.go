@@ -0,0 +1,37 @@
+package pstree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// defaultKillSignal is what kill sends without --signal, the only signal
+// Windows has
+const defaultKillSignal = "KILL"
+
+// parseSignal parses a signal name, only KILL can be sent on Windows
+func parseSignal(spec string) (os.Signal, string, error) {
+	switch strings.TrimPrefix(strings.ToUpper(spec), "SIG") {
+	case "KILL", "9":
+		return os.Kill, "SIGKILL", nil
+	}
+	return nil, "", fmt.Errorf("signal %q is not supported on Windows, only KILL", spec)
+}
+
+// processAlive reports whether a process is still running
+func processAlive(pid int) bool {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(handle)
+	var code uint32
+	if err := windows.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	// STILL_ACTIVE
+	return code == 259
+}
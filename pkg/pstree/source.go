@@ -0,0 +1,259 @@
+package pstree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProcessSource provides snapshots of a process table. Implementations
+// return every process with PID, PPID and whatever else they know; the
+// hierarchy, filters and renderers work the same for all of them
+type ProcessSource interface {
+	Processes() ([]Process, error)
+}
+
+// SourceFunc adapts a plain function to a ProcessSource
+type SourceFunc func() ([]Process, error)
+
+func (f SourceFunc) Processes() ([]Process, error) {
+	return f()
+}
+
+// SourceFactory creates a source, arg is what follows "NAME:" in --source
+type SourceFactory func(arg string) (ProcessSource, error)
+
+// sourceFactory creates a built-in source for a tree, whose settings tell
+// what to read
+type sourceFactory func(t *Tree, arg string) (ProcessSource, error)
+
+var (
+	sources = map[string]sourceFactory{}
+
+	// source used by --source auto when not on Linux, platform files
+	// with a native backend override it
+	defaultSource = "ps"
+)
+
+// RegisterSource makes a process source selectable with --source NAME[:ARG]
+func RegisterSource(name string, factory SourceFactory) {
+	registerSource(name, func(t *Tree, arg string) (ProcessSource, error) {
+		return factory(arg)
+	})
+}
+
+// registerSource registers a built-in source, which reads the settings
+// of the tree being loaded
+func registerSource(name string, factory sourceFactory) {
+	if _, dup := sources[name]; dup {
+		panic("pstree: process source registered twice: " + name)
+	}
+	sources[name] = factory
+}
+
+func init() {
+	registerSource("proc", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcessesLinux), nil
+	})
+	registerSource("ps", func(t *Tree, arg string) (ProcessSource, error) {
+		return SourceFunc(t.getProcesses), nil
+	})
+	registerSource("procfs", func(t *Tree, arg string) (ProcessSource, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("the procfs source needs a directory, e.g. procfs:testdata/proc")
+		}
+		return procfsSource{t: t, root: arg}, nil
+	})
+	registerSource("file", func(t *Tree, arg string) (ProcessSource, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("the file source needs a path, e.g. file:ps.txt")
+		}
+		return fileSource{t: t, path: arg}, nil
+	})
+	registerSource("stdin", func(t *Tree, arg string) (ProcessSource, error) {
+		return fileSource{t: t, path: "-"}, nil
+	})
+}
+
+// sourceNames lists the registered sources, for help texts
+func sourceNames() []string {
+	names := []string{"auto"}
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// resolveSourceName returns the registered name a --source value selects
+func resolveSourceName(spec string) string {
+	name, _, _ := strings.Cut(spec, ":")
+	if name == "auto" || name == "" {
+		if runtime.GOOS == "linux" {
+			return "proc"
+		}
+		return defaultSource
+	}
+	return name
+}
+
+// openSource creates the source described by a --source value
+func (t *Tree) openSource(spec string) (ProcessSource, error) {
+	name := resolveSourceName(spec)
+	_, arg, _ := strings.Cut(spec, ":")
+
+	factory, ok := sources[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown process source %q (want %s)", name, strings.Join(sourceNames(), ", "))
+	}
+	return factory(t, arg)
+}
+
+// procfsSource reads a /proc layout found under another directory, a
+// copy or the fixtures of the tests. The owners come from the Uid line
+// of status, the directories of a copy belong to whoever copied them
+type procfsSource struct {
+	t    *Tree
+	root string
+}
+
+func (s procfsSource) Processes() ([]Process, error) {
+	list, err := s.t.readProcRoot(s.root)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		data, err := os.ReadFile(filepath.Join(s.root, strconv.Itoa(list[i].PID), "status"))
+		if err != nil {
+			continue
+		}
+		// real, effective, saved and filesystem uids
+		if uids := statusInts(string(data), "Uid"); len(uids) > 1 {
+			list[i].UID = uids[1]
+			list[i].Owner = s.t.ownerName(uids[1])
+		}
+	}
+	return list, nil
+}
+
+// fileSource reads a process table saved to a file, "-" being stdin.
+// It accepts either a recorded history, whose last snapshot is used, or
+// the output of ps with a header line, e.g. ps -ef or ps -eo pid,ppid,args
+type fileSource struct {
+	t    *Tree
+	path string
+}
+
+func (s fileSource) Processes() ([]Process, error) {
+	var data []byte
+	var err error
+	if s.path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(s.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		lines := bytes.Split(trimmed, []byte("\n"))
+		var snap TableSnapshot
+		if err := json.Unmarshal(lines[len(lines)-1], &snap); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.path, err)
+		}
+		s.t.useSnapshot(snap)
+		return s.t.procs, nil
+	}
+
+	return s.t.parsePsTable(bytes.NewReader(data))
+}
+
+// parsePsTable parses ps output using its header line to locate the
+// columns. The command column must come last as it may contain spaces
+func (t *Tree) parsePsTable(r io.Reader) ([]Process, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("no input")
+	}
+
+	columns := map[string]int{}
+	cmdColumn := -1
+	for i, name := range strings.Fields(strings.ToUpper(scanner.Text())) {
+		switch name {
+		case "UID", "USER", "UNAME", "OWNER", "RUSER":
+			columns["user"] = i
+		case "PID":
+			columns["pid"] = i
+		case "PPID":
+			columns["ppid"] = i
+		case "PGID", "PGRP":
+			columns["pgid"] = i
+		case "THCNT", "NLWP", "THCOUNT", "WQ":
+			columns["threads"] = i
+		case "COMMAND", "CMD", "ARGS", "COMM":
+			cmdColumn = i
+		}
+	}
+	if _, ok := columns["pid"]; !ok {
+		return nil, fmt.Errorf("no PID column in header")
+	}
+	if _, ok := columns["ppid"]; !ok {
+		return nil, fmt.Errorf("no PPID column in header")
+	}
+
+	list := make([]Process, 0)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+
+		var proc Process
+		var err error
+		if proc.PID, err = strconv.Atoi(field("pid")); err != nil {
+			continue
+		}
+		proc.PPID, _ = strconv.Atoi(field("ppid"))
+		proc.PGID, _ = strconv.Atoi(field("pgid"))
+		proc.ThreadCount = 1
+		if threads, err := strconv.Atoi(field("threads")); err == nil && threads > 0 {
+			proc.ThreadCount = threads
+		}
+
+		owner := field("user")
+		if uid, err := strconv.Atoi(owner); err == nil {
+			proc.UID = uid
+			owner = t.ownerName(uid)
+		}
+		proc.Owner = owner
+
+		if cmdColumn != -1 && cmdColumn < len(fields) {
+			proc.Cmd = strings.Join(fields[cmdColumn:], " ")
+		}
+
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		list = append(list, proc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
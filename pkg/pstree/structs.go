@@ -0,0 +1,332 @@
+package pstree
+
+import "time"
+
+// version is set at build time with -ldflags -X
+var version = "1.0.0"
+
+// TreeChars defines the characters used for drawing the tree
+type TreeChars struct {
+	S2   string // String between header and pid
+	P    string // dito, when parent of printed children
+	PGL  string // Process group leader
+	NPGL string // No process group leader
+	BarC string // bar for line with child
+	Bar  string // bar for line without child
+	BarL string // bar for last child
+	SG   string // Start graphics (alt char set)
+	EG   string // End graphics (alt char set)
+	Init string // Init string sent at the beginning
+}
+
+// Graphics modes
+const (
+	GraphicsASCII = iota
+	GraphicsPC850
+	GraphicsVT100
+	GraphicsUTF8
+	GraphicsRounded
+	GraphicsHeavy
+	GraphicsDouble
+	// the glyphs of the custom-graphics section of the config file
+	GraphicsCustom
+)
+
+var treeChars = []TreeChars{
+	// ASCII
+	{"--", "-+", "=", "-", "|", "|", "\\", "", "", ""},
+	// PC850
+	{"\304\304", "\304\302", "\372", "\304", "\303", "\263", "\300", "", "", ""},
+	// VT100
+	{"qq", "qw", "`", "q", "t", "x", "m", "\016", "\017", "\033(B\033)0"},
+	// UTF8
+	{"\342\224\200\342\224\200", "\342\224\200\342\224\254", "=", "\342\224\200", "\342\224\234", "\342\224\202", "\342\224\224", "", "", ""},
+	// rounded
+	{"──", "─┬", "=", "─", "├", "│", "╰", "", "", ""},
+	// heavy
+	{"━━", "━┳", "=", "━", "┣", "┃", "┗", "", "", ""},
+	// double
+	{"══", "═╦", "=", "═", "╠", "║", "╚", "", "", ""},
+	// custom, UTF8 until the config file says otherwise
+	{"──", "─┬", "=", "─", "├", "│", "└", "", "", ""},
+}
+
+// Process represents a single process
+type Process struct {
+	UID         int    `json:"uid"`
+	PID         int    `json:"pid"`
+	PPID        int    `json:"ppid"`
+	PGID        int    `json:"pgid"`
+	Owner       string `json:"owner"`
+	Cmd         string `json:"cmd"`
+	ThreadCount int    `json:"threads"`
+	// command name as known to the kernel, and path of the executable
+	Comm string `json:"comm,omitempty"`
+	Exe  string `json:"exe,omitempty"`
+	// state letter from /proc/PID/stat, e.g. R, S, Z or T
+	State string `json:"state,omitempty"`
+	// session id and controlling terminal, e.g. pts/3
+	SID int    `json:"sid,omitempty"`
+	TTY string `json:"tty,omitempty"`
+	// start time in clock ticks since boot, 0 when unknown
+	StartTime uint64 `json:"start,omitempty"`
+	// start time, zero when unknown
+	Started time.Time `json:"started,omitzero"`
+	// user+system cpu time in clock ticks
+	CPUTicks uint64 `json:"cpu_ticks,omitempty"`
+	// cpu usage over the last sampling interval, in percent of one cpu
+	CPUPercent float64 `json:"cpu,omitempty"`
+	// nice value and priority, and on Linux the real-time priority and
+	// the scheduling policy, e.g. OTHER or FIFO
+	Nice       int    `json:"nice,omitempty"`
+	Priority   int    `json:"priority,omitempty"`
+	RTPriority int    `json:"rtprio,omitempty"`
+	Policy     string `json:"policy,omitempty"`
+	// effective capabilities, a mask of capNames bits
+	CapEff uint64 `json:"cap_eff,omitempty"`
+	// the executable was deleted, or is setuid or setgid, with --audit
+	ExeDeleted bool `json:"exe_deleted,omitempty"`
+	Setuid     bool `json:"setuid,omitempty"`
+	// working directory and root directory, empty when unknown
+	Cwd  string `json:"cwd,omitempty"`
+	Root string `json:"root,omitempty"`
+	// bytes read from and written to storage, when IOKnown, and the rates
+	// between watch refreshes, when IORates
+	ReadBytes  uint64  `json:"read_bytes,omitempty"`
+	WriteBytes uint64  `json:"write_bytes,omitempty"`
+	IOKnown    bool    `json:"-"`
+	ReadRate   float64 `json:"-"`
+	WriteRate  float64 `json:"-"`
+	IORates    bool    `json:"-"`
+	// open file descriptors, -1 when they can't be listed, Linux only
+	FDs int `json:"fds,omitempty"`
+	// addresses of the listening sockets, e.g. ":80" or "[::1]:53/udp"
+	Ports []string `json:"ports,omitempty"`
+	// oom_score and oom_score_adj, Linux only
+	OOMScore    int `json:"oom_score,omitempty"`
+	OOMScoreAdj int `json:"oom_score_adj,omitempty"`
+	// resident set size, virtual size and swapped out memory in bytes
+	RSS  uint64 `json:"rss,omitempty"`
+	VSZ  uint64 `json:"vsz,omitempty"`
+	Swap uint64 `json:"swap,omitempty"`
+	// the usage of the process and its descendants, with --cumulative
+	SubtreeCPU  float64 `json:"-"`
+	SubtreeRSS  uint64  `json:"-"`
+	SubtreeVSZ  uint64  `json:"-"`
+	SubtreeSwap uint64  `json:"-"`
+	// 1-based position among its siblings by start time
+	BirthOrder int `json:"-"`
+	// number of direct children and of all descendants
+	Children    int `json:"-"`
+	Descendants int `json:"-"`
+	// controller -> cgroup path, the unified (v2) hierarchy uses ""
+	Cgroups map[string]string `json:"cgroups,omitempty"`
+	// primary group, then supplementary groups
+	GIDs []int `json:"gids,omitempty"`
+	// pid in each nested pid namespace, host first, from NSpid
+	NSPids []int `json:"nspids,omitempty"`
+	// id of the docker/podman/containerd/cri-o container, from the cgroups
+	Container string `json:"container,omitempty"`
+	// uid of the kubernetes pod, from the cgroups
+	Pod string `json:"pod,omitempty"`
+	// launchd label of the job a macOS process runs, systemd units are
+	// derived from Cgroups
+	Unit string `json:"unit,omitempty"`
+	// FreeBSD jail id, 0 for the host
+	Jail int `json:"jail,omitempty"`
+	// Solaris/illumos zone id, 0 for the global zone
+	Zone int `json:"zone,omitempty"`
+
+	// kernel thread, i.e. kthreadd or one of its children
+	Kernel bool `json:"kernel,omitempty"`
+	// a thread of process PPID, listed with --threads
+	Thread bool `json:"-"`
+	// synthetic namespace header inserted by -N/--ns
+	Group bool `json:"-"`
+
+	// line prints when true
+	Print bool `json:"-"`
+	// number of identical siblings folded into this one, printed as N*[name]
+	Compacted int `json:"-"`
+	// highlightMatch or highlightAncestor with --highlight and -H
+	Highlight int `json:"-"`
+	// meta data to create and filter the tree structure
+	ParentIdx int `json:"-"`
+	ChildIdx  int `json:"-"`
+	// next node at same level
+	SisterIdx int `json:"-"`
+}
+
+// Config holds the application configuration
+type Config struct {
+	// show all processes
+	AOption bool
+	// filter on a given user
+	UOption bool
+	// print uids instead of user names
+	NumericOwners bool
+	// show pids in the rendering, which also turns compaction off
+	POption bool
+	// print identical sibling leaves one by one instead of as N*[name]
+	NoCompact bool
+	// --color: auto, always or never
+	Color string
+	// debug option
+	DOption bool
+	// For wide output (no width truncation)
+	WOption bool
+	// forced output width in columns, 0 to detect it
+	Width int
+	// filter processes on these owners
+	SearchOwners []string
+	// hide processes of these users
+	ExcludeOwners []string
+	// show only processes in this group
+	Group string
+	// show only processes on this terminal, or in this session
+	TTY     string
+	Session int
+	// show the controlling terminal of each process
+	ShowTTY bool
+	// show only processes above these %cpu and rss thresholds, cpu
+	// usage is measured over Sample
+	MinCPU float64
+	MinRSS string
+	Sample time.Duration
+	// show only processes matching this expression, see compileWhere
+	Where string
+	// optional strings to filter start processes
+	SearchStrs []string
+	// match SearchStrs as regexps, and/or ignoring case
+	Regex      bool
+	IgnoreCase bool
+	// compare patterns with the comm, exe or cmdline of processes
+	MatchField string
+	// hide the subtrees of processes matching these patterns
+	Excludes []string
+	// emphasize the processes matching these patterns or pids, and
+	// their ancestors
+	Highlights    []string
+	HighlightPids []int
+	// emphasize pstree itself and its ancestors
+	HighlightSelf bool
+	// optional pids to start from, default parent pid
+	SearchPids []int
+	// levels of ancestors and descendants shown around matches, -1 for all
+	Context int
+	// show the ancestors and descendants of this pid only
+	ShowParents int
+	// files listing more pids to start from, - for stdin, and pidfiles
+	PidsFrom []string
+	PidFiles []string
+	// maximum tree depth
+	MaxLDepth int
+	// show each child's ordinal position by start time under its parent
+	BirthOrder bool
+	// show direct children and total descendants counts
+	ShowCounts bool
+	// show descendants counts as (+N) badges
+	Counts bool
+	// print totals after the tree
+	Summary bool
+	// print one forest per user
+	ByUser bool
+	// show the cpu and memory usage of processes, and the totals of
+	// their subtrees
+	ShowCPU    bool
+	ShowMem    bool
+	ShowVSZ    bool
+	ShowSwap   bool
+	Cumulative bool
+	// --heat: color the lines by cpu or mem use
+	Heat string
+	// show the nice value, priority and scheduling policy of processes
+	Sched bool
+	// show the OOM killer scores of processes
+	OOM bool
+	// show the effective capabilities of processes
+	Caps bool
+	// flag the processes running deleted or setuid executables
+	Audit bool
+	// show the working directory and chroot of processes
+	Cwd bool
+	// show these environment variables of processes
+	Env []string
+	// show the storage io of processes
+	IO bool
+	// show the listening sockets of processes
+	Net bool
+	// show the open file descriptors of processes, or only those with
+	// at least MinFDs
+	FDs    bool
+	MinFDs int
+	// show the elapsed time or start time of processes, per AgeFormat
+	Age       bool
+	AgeFormat string
+	// annotate subtrees with their cgroup cpu quota and cpuset
+	CPUQuota bool
+	// annotate cgroup subtrees with their memory use and cpu pressure
+	CgroupStats bool
+
+	// delay between refreshes in watch mode
+	Interval time.Duration
+	// process source, NAME or NAME:ARG, see RegisterSource
+	Source string
+	// workers reading /proc, 0 for one per cpu
+	Jobs int
+	// read the command lines of the printed processes only, the rest of
+	// the process table is still read whole
+	LowMemory bool
+	// times the processes whose parent vanished are read again
+	Retries int
+	// how watch mode notices changes: poll, fast or netlink
+	WatchStrategy string
+	// pid followed in watch mode until it exits, -1 when not following
+	FollowPid int
+	// commands run in watch mode for the printed processes appearing and
+	// disappearing, {} stands for the pid
+	OnNew  string
+	OnExit string
+	// output format: text, json, dot, gantt or gantt-svg
+	Output string
+	// recorded history file used by the gantt outputs
+	History string
+
+	// character set selector in treeChars
+	Graphics int
+	// append the pid inside the process's pid namespace
+	NSPids bool
+	// show only the processes of a FreeBSD jail or Solaris zone, by
+	// name or id, or group processes by jail or zone
+	Jail       string
+	Zone       string
+	GroupJails bool
+	// annotate systemd units, or group processes by slice and unit
+	ShowUnit   bool
+	GroupUnits bool
+	// insert a node above the processes of each kubernetes pod
+	GroupPods bool
+	// insert a node above the processes of each container
+	GroupContainers bool
+	// name containers by asking the docker or podman socket
+	ResolveContainers bool
+	// group processes by this namespace type
+	Namespace string
+	// show only processes in these states
+	States []string
+	// prune the kernel threads
+	HideKernel bool
+	// list threads as children of their process
+	Threads bool
+	// columns shown for each process, see columnTable
+	Format []string
+	// columnar layout with a header line, and a legend of the markers
+	HeaderRow bool
+	Legend    bool
+	// verify the terminal renders the graphics chars before using them
+	ProbeGlyphs bool
+	// terminal width in columns
+	Columns int
+	// character set used to render the tree
+	TreeChar *TreeChars
+}
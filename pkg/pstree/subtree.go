@@ -0,0 +1,69 @@
+package pstree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// subtreeIndices returns idx followed by all of its descendants, ordered
+// so that every parent comes before its children (breadth first)
+func (t *Tree) subtreeIndices(idx int) []int {
+	out := []int{idx}
+	for i := 0; i < len(out); i++ {
+		child := t.procs[out[i]].ChildIdx
+		for child != -1 {
+			out = append(out, child)
+			child = t.procs[child].SisterIdx
+		}
+	}
+	return out
+}
+
+// resolveTargets snapshots the process table and returns the indices
+// of pid, plus its descendants when recursive is set
+func (t *Tree) resolveTargets(pid int, recursive bool) ([]int, error) {
+	if err := t.loadProcesses(); err != nil {
+		return nil, err
+	}
+	t.makeTreeHierarchy()
+
+	idx := t.getPidIndex(pid)
+	if idx == -1 {
+		return nil, fmt.Errorf("no such process: %d", pid)
+	}
+	if !recursive {
+		return []int{idx}, nil
+	}
+	return t.subtreeIndices(idx), nil
+}
+
+// readPidFile reads the whitespace separated pids of a pidfile or of
+// pgrep output, "-" reads stdin
+func readPidFile(path string) ([]int, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, field := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pid %q", path, field)
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("%s: no pids", path)
+	}
+	return pids, nil
+}
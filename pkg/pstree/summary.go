@@ -0,0 +1,77 @@
+package pstree
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// printSummary prints totals of the processes printed below roots:
+// processes and threads, per user and per state, the depth of the tree
+// and its widest fan-out
+func (t *Tree) printSummary(roots []int) {
+	var processes, threads, maxDepth, fanOut int
+	widest := -1
+	users := map[string]int{}
+	states := map[string]int{}
+
+	var shown []int
+	for _, root := range roots {
+		if t.procs[root].Print {
+			shown = append(shown, t.subtreeIndices(root)...)
+		}
+	}
+
+	for _, i := range shown {
+		process := t.procs[i]
+		if process.Group || process.Thread {
+			continue
+		}
+		// a compacted process stands for its identical siblings too
+		n := process.Compacted + 1
+		processes += n
+		threads += n * max(process.ThreadCount, 1)
+		users[process.Owner] += n
+		states[orDash(process.State)] += n
+
+		depth := 0
+		for parent := process.ParentIdx; parent != -1; parent = t.procs[parent].ParentIdx {
+			if !t.procs[parent].Group {
+				depth++
+			}
+		}
+		maxDepth = max(maxDepth, depth)
+
+		children := 0
+		for child := process.ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+			if !t.procs[child].Thread {
+				children += t.procs[child].Compacted + 1
+			}
+		}
+		if children > fanOut {
+			fanOut, widest = children, i
+		}
+	}
+
+	fmt.Fprintf(t.output, "summary: %d processes, %d threads, max depth %d", processes, threads, maxDepth)
+	if widest != -1 {
+		fmt.Fprintf(t.output, ", widest fan-out %d (%d %s)", fanOut, t.procs[widest].PID, commandName(t.procs[widest].Cmd))
+	}
+	fmt.Fprintln(t.output)
+	fmt.Fprintln(t.output, "  users: "+countList(users))
+	fmt.Fprintln(t.output, "  states: "+countList(states))
+}
+
+// countList formats counts by decreasing count, e.g. "root 180, www 20"
+func countList(counts map[string]int) string {
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, fmt.Sprintf("%s %d", key, counts[key]))
+	}
+	return strings.Join(entries, ", ")
+}
@@ -0,0 +1,83 @@
+package pstree
+
+import (
+	"path"
+	"strings"
+)
+
+// unitSuffixes are the systemd unit types that own processes
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+
+// systemdUnit returns the unit a process belongs to and the cgroup path
+// of the slice holding that unit, from the systemd cgroup hierarchy:
+// name=systemd with cgroup v1, the unified one with v2
+func systemdUnit(process Process) (slice, unit string) {
+	cgroupPath, ok := process.Cgroups["name=systemd"]
+	if !ok {
+		cgroupPath = process.Cgroups[""]
+	}
+
+	// the innermost unit, e.g. user@1000.service/app.slice/foo.service
+	for dir := cgroupPath; dir != "/" && dir != "." && dir != ""; dir = path.Dir(dir) {
+		name := path.Base(dir)
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return path.Dir(dir), name
+			}
+		}
+	}
+	return "", ""
+}
+
+// processUnit returns the service of a process: its launchd label on
+// macOS, its systemd unit elsewhere
+func processUnit(process Process) string {
+	if process.Unit != "" {
+		return process.Unit
+	}
+	_, unit := systemdUnit(process)
+	return unit
+}
+
+// unitAnnotation returns "(UNIT)" for processes that start a systemd
+// unit or launchd job, i.e. whose unit differs from their parent's
+func (t *Tree) unitAnnotation(idx int) string {
+	unit := processUnit(t.procs[idx])
+	if unit == "" {
+		return ""
+	}
+	if parent := t.procs[idx].ParentIdx; parent != -1 && processUnit(t.procs[parent]) == unit {
+		return ""
+	}
+	return "(" + unit + ")"
+}
+
+// groupByUnit arranges the tree like systemd-cgls: a node per slice,
+// holding a node per unit, holding the unit's processes
+func (t *Tree) groupByUnit() {
+	sliceOf := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if !p.Thread {
+			slice, _ := systemdUnit(p)
+			sliceOf[p.PID] = slice
+		}
+	}
+	t.insertGroups(sliceOf, sliceName)
+
+	unitOf := make(map[int]string, t.nProc)
+	for _, p := range t.procs {
+		if !p.Thread {
+			_, unit := systemdUnit(p)
+			unitOf[p.PID] = unit
+		}
+	}
+	t.insertGroups(unitOf, func(unit string) string { return unit })
+}
+
+// sliceName names a slice by its cgroup path, e.g. user.slice/user-1000.slice
+func sliceName(slice string) string {
+	if slice == "/" {
+		return "-.slice"
+	}
+	return strings.TrimPrefix(slice, "/")
+}
@@ -0,0 +1,125 @@
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	maxLine = 8192
+
+	// how long to wait for the terminal to report the cursor position
+	probeTimeout = 300 * time.Millisecond
+)
+
+func (t *Tree) CalculateTerminalWidth() {
+	// Get terminal width
+	t.config.Columns = t.getTerminalWidth()
+	if t.config.Columns == 0 {
+		t.config.Columns = maxLine - 1
+	}
+
+	if t.config.Columns >= maxLine {
+		t.config.Columns = maxLine - 1
+	}
+
+	log.Debugf("columns: %d", t.config.Columns)
+}
+
+// getTerminalWidth gets the terminal width
+func (t *Tree) getTerminalWidth() int {
+
+	if t.config.Width > 0 {
+		return t.config.Width
+	}
+
+	if t.config.WOption {
+		return maxLine - 1
+	}
+
+	// Like ps, don't truncate when piped or redirected unless asked to
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		if cols := os.Getenv("COLUMNS"); cols != "" {
+			if c, err := strconv.Atoi(cols); err == nil {
+				return c
+			}
+		}
+		return maxLine - 1
+	}
+
+	// Try to get terminal size
+
+	// method 1 : term pkg
+	if width, _, err := term.GetSize(os.Stdout.Fd()); err == nil {
+		return width
+	}
+
+	// method 2: env variable
+	if cols := os.Getenv("COLUMNS"); cols != "" {
+		if c, err := strconv.Atoi(cols); err == nil {
+			return c
+		}
+	}
+
+	return 80 // default
+}
+
+// probeGlyphs prints the box drawing characters of the current graphics
+// mode on the controlling terminal and asks for the cursor position
+// (DSR 6). It returns false when the cursor didn't advance by exactly one
+// column per glyph, i.e. the font or locale can't render them properly.
+// Terminals that don't answer are assumed to be fine
+func (t *Tree) probeGlyphs() bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return true
+	}
+	defer tty.Close()
+
+	state, err := term.MakeRaw(tty.Fd())
+	if err != nil {
+		return true
+	}
+	defer term.Restore(tty.Fd(), state)
+
+	glyphs := []string{t.config.TreeChar.BarC, t.config.TreeChar.Bar, t.config.TreeChar.BarL, t.config.TreeChar.P}
+	sample := t.config.TreeChar.SG + strings.Join(glyphs, "") + t.config.TreeChar.EG
+	width := 0
+	for _, glyph := range glyphs {
+		width += len([]rune(glyph))
+	}
+
+	// draw at the start of the line, query, then erase the sample
+	fmt.Fprint(tty, "\r"+sample+"\033[6n")
+	defer fmt.Fprint(tty, "\r\033[K")
+
+	reply := make(chan string, 1)
+	go func() {
+		// ESC [ row ; col R
+		answer, _ := bufio.NewReader(tty).ReadString('R')
+		reply <- answer
+	}()
+
+	var answer string
+	select {
+	case answer = <-reply:
+	case <-time.After(probeTimeout):
+		log.Debugf("probeGlyphs: no cursor position report")
+		return true
+	}
+
+	_, position, found := strings.Cut(strings.TrimSuffix(answer, "R"), ";")
+	col, err := strconv.Atoi(position)
+	if !found || err != nil {
+		return true
+	}
+	log.Debugf("probeGlyphs: cursor at column %d, expected %d", col, width+1)
+	return col == width+1
+}
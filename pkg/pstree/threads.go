@@ -0,0 +1,57 @@
+package pstree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// addThreads appends the threads of the loaded processes as children of
+// their process, named {comm} like pstree -t. Threads are only listed by
+// the Linux /proc source
+func (t *Tree) addThreads() {
+	if resolveSourceName(t.config.Source) != "proc" {
+		return
+	}
+
+	for i := range t.nProc {
+		process := t.procs[i]
+		if process.ThreadCount < 2 {
+			continue
+		}
+
+		taskDir := filepath.Join("/proc", strconv.Itoa(process.PID), "task")
+		tasks, err := os.ReadDir(taskDir)
+		if err != nil {
+			continue
+		}
+		for _, task := range tasks {
+			tid, err := strconv.Atoi(task.Name())
+			if err != nil || tid == process.PID {
+				continue
+			}
+			comm, err := os.ReadFile(filepath.Join(taskDir, task.Name(), "comm"))
+			if err != nil {
+				// the thread exited meanwhile
+				continue
+			}
+
+			t.procs = append(t.procs, Process{
+				UID:         process.UID,
+				PID:         tid,
+				PPID:        process.PID,
+				PGID:        process.PGID,
+				Owner:       process.Owner,
+				Cmd:         "{" + strings.TrimSpace(string(comm)) + "}",
+				ThreadCount: 1,
+				Cgroups:     process.Cgroups,
+				Thread:      true,
+				ParentIdx:   -1,
+				ChildIdx:    -1,
+				SisterIdx:   -1,
+			})
+		}
+	}
+	t.indexProcesses()
+}
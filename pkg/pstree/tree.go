@@ -0,0 +1,1402 @@
+package pstree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/log"
+)
+
+// Tree is a snapshot of the process table with the settings it is
+// filtered and rendered with. Trees are independent of each other, each
+// is used by one goroutine at a time
+type Tree struct {
+	// This holds the command line options
+	config Config
+
+	// This holds the output of 'ps'
+	procs []Process
+	// number of discovered processes
+	nProc int
+	// index in procs of each pid, see indexProcesses
+	pidIndex map[int]int
+
+	// current rendering depth
+	atLDepth int
+
+	// where the tree is rendered to, and the renderer used instead of
+	// the --output format, e.g. the watch screen
+	output   io.Writer
+	renderer Renderer
+
+	// the compiled search arguments, --exclude and --highlight patterns
+	searchPatterns    []*regexp.Regexp
+	excludePatterns   []*regexp.Regexp
+	highlightPatterns []*regexp.Regexp
+	// the resolved -u and --not-user arguments
+	searchUsers   []userFilter
+	excludedUsers []userFilter
+	// the resolved -G/--group argument
+	searchGID int
+	// the parsed --min-rss argument, in bytes
+	minRSS uint64
+	// the compiled --where expression, nil when unset, and whether it
+	// reads the cpu usage, which must then be sampled, the executable
+	// path, which must then be read, or the command line, which
+	// --low-memory must then read upfront
+	whereFilter func(*Process) bool
+	whereCPU    bool
+	whereExe    bool
+	whereCmd    bool
+	// the columns printed, nil for the default node labels
+	activeColumns []string
+
+	// set once CPUPercent holds a measurement
+	cpuSampled bool
+	// the highest oom_score of the loaded processes, the one the OOM
+	// killer picks next
+	oomMaxScore int
+	// the highest --heat value of the printed processes
+	heatMax float64
+	// processes read by the last lazy load, by /proc directory
+	procCache map[string]Process
+	// maps the inodes of the listening sockets to their address, e.g.
+	// ":80" or "127.0.0.1:53/udp", read once per snapshot
+	listenSockets map[uint64]string
+	// cpu quota annotations, keyed by cgroupCPUKey, and resource usage
+	// annotations, keyed by cgroupStatsKey
+	cpuQuotaCache    map[string]string
+	cgroupStatsCache map[string]string
+	// the names resolved with --resolve-containers, "" when no runtime
+	// knows the container
+	containerNames map[string]string
+	// pod uid -> namespace/name, and container id -> container name,
+	// read once from the kubelet log directories
+	kubePods       map[string]string
+	kubeContainers map[string]string
+	// jail id -> name and zone id -> name, listed once with jls and zoneadm
+	jailNameCache map[int]string
+	zoneNameCache map[int]string
+	// uid -> user name lookups of the current snapshot, shared by the
+	// /proc readers
+	ownerNames   map[int]string
+	ownerNamesMu sync.Mutex
+}
+
+// newTree returns an empty tree with the default settings, printing to
+// stdout
+func newTree() *Tree {
+	return &Tree{
+		config: Config{
+			AOption:     false,
+			MaxLDepth:   100,
+			Graphics:    GraphicsASCII,
+			TreeChar:    &treeChars[GraphicsASCII],
+			FollowPid:   -1,
+			ShowParents: -1,
+			Context:     -1,
+			Session:     -1,
+		},
+		output:           os.Stdout,
+		procCache:        map[string]Process{},
+		cpuQuotaCache:    map[string]string{},
+		cgroupStatsCache: map[string]string{},
+		containerNames:   map[string]string{},
+	}
+}
+
+// nodeLabel formats the text printed for a process after the tree graphics
+func (t *Tree) nodeLabel(idx int) string {
+	if process := t.procs[idx]; process.Compacted > 0 {
+		// thread names already come in braces
+		name := process.Cmd
+		if !process.Thread {
+			name = processName(process)
+		}
+		return fmt.Sprintf("%d*[%s]", process.Compacted+1, name)
+	}
+	return t.processLabel(idx, true)
+}
+
+// processLabel formats the label of a process, without its pid unless
+// showPID is set
+func (t *Tree) processLabel(idx int, showPID bool) string {
+	process := t.procs[idx]
+	if process.Group {
+		return process.Cmd
+	}
+
+	var thread string
+	// with --threads, threads are nodes of their own
+	if process.ThreadCount > 1 && !t.config.Threads {
+		thread = fmt.Sprintf("[%d]", process.ThreadCount)
+	}
+
+	var birth string
+	if t.config.BirthOrder && process.BirthOrder > 0 {
+		birth = fmt.Sprintf("#%d ", process.BirthOrder)
+	}
+
+	var out string
+	if t.config.HeaderRow {
+		// the other fields are in the columns
+		out = birth + process.Cmd
+	} else if len(t.activeColumns) > 0 {
+		out = t.columnLabel(process, birth)
+	} else {
+		var tty string
+		if t.config.ShowTTY {
+			tty = " " + ttyName(process)
+		}
+		out = fmt.Sprintf("%s%s%s %s%s", birth, process.Owner, tty, thread, process.Cmd)
+		if showPID {
+			out = fmt.Sprintf("%05d%s %s", process.PID, t.nsPIDSuffix(process), out)
+		}
+	}
+
+	switch process.State {
+	case "Z":
+		out += " <defunct>"
+	case "T":
+		out += " <stopped>"
+	case "t":
+		out += " <traced>"
+	}
+
+	if t.config.Audit {
+		if markers := auditMarkers(process); markers != "" {
+			out += " " + markers
+		}
+	}
+
+	// the group node above already names the container
+	if !t.config.GroupContainers {
+		if container := t.containerAnnotation(idx); container != "" {
+			out += " " + container
+		}
+	}
+
+	if t.config.ShowUnit && !t.config.GroupUnits {
+		if unit := t.unitAnnotation(idx); unit != "" {
+			out += " " + unit
+		}
+	}
+
+	if t.config.ShowCounts {
+		out += fmt.Sprintf(" (c:%d d:%d)", process.Children, process.Descendants)
+	}
+
+	if t.config.Counts && process.Descendants > 0 {
+		// --level stops below this node
+		if t.atLDepth == t.config.MaxLDepth && process.ChildIdx != -1 {
+			out += fmt.Sprintf(" (+%d hidden)", process.Descendants)
+		} else {
+			out += fmt.Sprintf(" (+%d)", process.Descendants)
+		}
+	}
+
+	if t.config.ShowCPU && !process.Thread {
+		if cpu := t.cpuAnnotation(process); cpu != "" {
+			out += " " + cpu
+		}
+	}
+
+	if t.config.ShowMem && !process.Thread {
+		out += " " + t.memAnnotation(process)
+	}
+
+	if t.config.Sched {
+		out += " " + schedAnnotation(process)
+	}
+
+	if t.config.OOM && !process.Thread {
+		out += " " + oomAnnotation(process)
+	}
+
+	if t.config.FDs && !process.Thread {
+		out += " (fds " + fdCount(process) + ")"
+	}
+
+	if t.config.Caps && process.CapEff != 0 {
+		out += " (caps " + capsSummary(process.CapEff) + ")"
+	}
+
+	if t.config.Cwd && !process.Thread {
+		if process.Cwd != "" {
+			out += " (cwd " + process.Cwd + ")"
+		}
+		if process.Root != "" && process.Root != "/" {
+			out += " <chroot " + process.Root + ">"
+		}
+	}
+
+	if t.config.IO && !process.Thread {
+		out += " " + ioAnnotation(process)
+	}
+
+	if len(t.config.Env) > 0 && !process.Thread {
+		if env := t.envAnnotation(process); env != "" {
+			out += " " + env
+		}
+	}
+
+	if t.config.Net && len(process.Ports) > 0 {
+		out += " (" + strings.Join(process.Ports, ",") + ")"
+	}
+
+	if t.config.Age {
+		if age := t.ageAnnotation(process); age != "" {
+			out += " " + age
+		}
+	}
+
+	if t.config.CPUQuota {
+		if quota := t.cpuQuotaAnnotation(idx); quota != "" {
+			out += " " + quota
+		}
+	}
+
+	if t.config.CgroupStats {
+		if stats := t.cgroupStatsAnnotation(idx); stats != "" {
+			out += " " + stats
+		}
+	}
+
+	return out
+}
+
+// nsPIDSuffix returns "/PID" with the pid of a process in its own pid
+// namespace when --ns-pids is set and it differs from the host's
+func (t *Tree) nsPIDSuffix(process Process) string {
+	if !t.config.NSPids || len(process.NSPids) < 2 {
+		return ""
+	}
+	return "/" + strconv.Itoa(process.NSPids[len(process.NSPids)-1])
+}
+
+// ttyName returns the controlling terminal of a process, "?" for none
+// like ps
+func ttyName(process Process) string {
+	if process.TTY == "" {
+		return "?"
+	}
+	return process.TTY
+}
+
+// printLegend explains the markers the current options can print
+func (t *Tree) printLegend() {
+	legend := [][2]string{
+		{t.config.TreeChar.PGL, "process group leader"},
+	}
+	if len(t.activeColumns) == 0 {
+		legend = append(legend, [2]string{"[N]", "thread count, when more than one"})
+	}
+	legend = append(legend, [2]string{"<defunct>", "zombie, <stopped> and <traced> likewise"})
+	if t.config.Audit {
+		legend = append(legend, [2]string{"<deleted exe>", "running a deleted executable, in magenta"})
+		legend = append(legend, [2]string{"<setuid>", "running a setuid or setgid executable, in orange"})
+	}
+	if t.config.BirthOrder {
+		legend = append(legend, [2]string{"#N", "position among siblings by start time"})
+	}
+	if t.config.ShowCounts {
+		legend = append(legend, [2]string{"(c:N d:M)", "direct children and all descendants"})
+	}
+	if t.config.Counts {
+		legend = append(legend, [2]string{"(+N)", "descendants, (+N hidden) below the --level limit"})
+	}
+	if t.config.ShowCPU && !t.columnShown("cpu") {
+		legend = append(legend, [2]string{"(cpu P)", "cpu usage, in percent of one cpu"})
+	}
+	if t.config.ShowCPU && t.config.Cumulative {
+		legend = append(legend, [2]string{"(tree T)", "cpu usage of the process and its descendants"})
+	}
+	if t.config.Sched {
+		legend = append(legend, [2]string{"(OTHER nice N)", "scheduling policy, nice and priority, real-time ones in red"})
+	}
+	if t.config.Caps {
+		legend = append(legend, [2]string{"(caps C)", "effective capabilities, all,-cap_x when most are set"})
+	}
+	if t.config.Cwd {
+		legend = append(legend, [2]string{"(cwd D)", "working directory, <chroot R> when the root is not /"})
+	}
+	if t.config.IO {
+		legend = append(legend, [2]string{"(io r R w W)", "bytes read from and written to storage, and their rates in watch mode"})
+	}
+	if t.config.Net {
+		legend = append(legend, [2]string{"(:P,A:P/udp)", "listening tcp and udp sockets"})
+	}
+	if t.config.FDs {
+		legend = append(legend, [2]string{"(fds N)", "open file descriptors, ? when not permitted"})
+	}
+	if t.config.OOM {
+		legend = append(legend, [2]string{"(oom S adj A)", "oom_score and oom_score_adj, the next victim in red"})
+	}
+	if t.config.Age {
+		if t.config.AgeFormat == "start" {
+			legend = append(legend, [2]string{"(started T)", "start time of the process"})
+		} else {
+			legend = append(legend, [2]string{"(age D)", "time since the process started"})
+		}
+	}
+	if t.config.ShowMem {
+		if t.config.Cumulative {
+			legend = append(legend, [2]string{"(rss M/T)", "memory of the process, and of it and its descendants"})
+		} else {
+			legend = append(legend, [2]string{"(rss M)", "resident memory, likewise virtual size and swap"})
+		}
+	}
+	legend = append(legend, [2]string{"[ID]", "first process of a container, by short id or name"})
+	if t.config.ShowUnit && !t.config.GroupUnits {
+		legend = append(legend, [2]string{"(UNIT)", "first process of a systemd unit or launchd job"})
+	}
+	if t.config.CPUQuota {
+		legend = append(legend, [2]string{"[Q CPU on S]", "cgroup cpu quota Q and cpuset S"})
+	}
+
+	if t.config.CgroupStats {
+		legend = append(legend, [2]string{"[mem M cpu.pressure P]", "cgroup memory and cpu pressure over 10s"})
+	}
+
+	fmt.Fprintln(t.output, "legend:")
+	for _, entry := range legend {
+		fmt.Fprintf(t.output, "  %-14s %s\n", entry[0], entry[1])
+	}
+}
+
+// rootIndices returns the indices of the processes to print trees from
+func (t *Tree) rootIndices() ([]int, error) {
+	roots, err := t.searchRoots()
+	if err != nil || t.config.Context < 0 {
+		return roots, err
+	}
+
+	// with --context the marked branches may not reach up to the roots,
+	// their topmost marked processes become roots of their own
+	var contextRoots []int
+	for _, root := range roots {
+		for _, idx := range t.subtreeIndices(root) {
+			parent := t.procs[idx].ParentIdx
+			if t.procs[idx].Print && (idx == root || parent == -1 || !t.procs[parent].Print) {
+				contextRoots = append(contextRoots, idx)
+			}
+		}
+	}
+	return contextRoots, nil
+}
+
+// searchRoots returns the searched pids, leaving out those below another
+// one, or the top process
+func (t *Tree) searchRoots() ([]int, error) {
+	if len(t.config.SearchPids) == 0 {
+		top, err := t.getTopPID()
+		if err != nil {
+			return nil, err
+		}
+		if idx := t.getPidIndex(top); idx != -1 {
+			return []int{idx}, nil
+		}
+		return nil, nil
+	}
+
+	isRoot := map[int]bool{}
+	roots := []int{}
+	for _, pid := range t.config.SearchPids {
+		if idx := t.getPidIndex(pid); idx != -1 && !isRoot[idx] {
+			isRoot[idx] = true
+			roots = append(roots, idx)
+		}
+	}
+	return slices.DeleteFunc(roots, func(idx int) bool {
+		for parent := t.procs[idx].ParentIdx; parent != -1; parent = t.procs[parent].ParentIdx {
+			if isRoot[parent] {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// getTopPID finds the root process PID
+func (t *Tree) getTopPID() (int, error) {
+
+	// Look for PID 1
+	if t.getPidIndex(1) != -1 {
+		return 1, nil
+	}
+
+	// Look for PPID 0
+	for _, proc := range t.procs {
+		if proc.PPID == 0 {
+			return proc.PID, nil
+		}
+	}
+
+	// Look for PPID 1
+	for _, proc := range t.procs {
+		if proc.PPID == 1 {
+			return proc.PID, nil
+		}
+	}
+
+	// Look for PID == PPID
+	for _, proc := range t.procs {
+		if proc.PID == proc.PPID {
+			return proc.PID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no process found with PID == 1, PPID == 0, PPID == 1 or PID == PPID")
+}
+
+// indexProcesses counts the loaded processes and maps their pids to
+// their index, it must run whenever procs is replaced or grows
+func (t *Tree) indexProcesses() {
+	t.nProc = len(t.procs)
+	t.pidIndex = make(map[int]int, t.nProc)
+	for i := range t.procs {
+		// the last one wins, e.g. a thread listed after its process
+		t.pidIndex[t.procs[i].PID] = i
+	}
+}
+
+// getPidIndex finds the index of a process by PID
+func (t *Tree) getPidIndex(pid int) int {
+	if idx, ok := t.pidIndex[pid]; ok {
+		return idx
+	}
+	return -1
+}
+
+// resetTree clears the hierarchy and filtering state of the loaded
+// processes, so the same snapshot can be rendered again
+func (t *Tree) resetTree() {
+	for i := range t.procs {
+		t.procs[i].ParentIdx = -1
+		t.procs[i].ChildIdx = -1
+		t.procs[i].SisterIdx = -1
+		t.procs[i].Print = false
+		t.procs[i].Compacted = 0
+		t.procs[i].Children = 0
+		t.procs[i].Descendants = 0
+	}
+}
+
+// breakCycles finds the processes whose parents lead back to themselves,
+// which racy or inconsistent snapshots may contain, and moves each cycle
+// under an "(orphans)" node so it is neither lost nor walked forever.
+// A process that is its own parent is a cycle of one
+func (t *Tree) breakCycles() {
+	const (
+		unseen = iota
+		onPath
+		done
+	)
+	state := make([]int, len(t.procs))
+	var breakers []int
+	for i := range t.procs {
+		var path []int
+		j := i
+		for j != -1 && state[j] == unseen {
+			state[j] = onPath
+			path = append(path, j)
+			parent := t.getPidIndex(t.procs[j].PPID)
+			if parent == j && t.procs[j].PID == 0 {
+				// the idle or swapper process is its own parent
+				parent = -1
+			}
+			j = parent
+		}
+		if j != -1 && state[j] == onPath {
+			// the path ran into itself, the cycle starts at j
+			cycle := path[slices.Index(path, j):]
+			breaker := slices.MinFunc(cycle, func(a, b int) int { return t.procs[a].PID - t.procs[b].PID })
+			breakers = append(breakers, breaker)
+		}
+		for _, k := range path {
+			state[k] = done
+		}
+	}
+	for _, idx := range breakers {
+		log.Warnf("pid %d: parent pid %d leads back to it, moved under %s", t.procs[idx].PID, t.procs[idx].PPID, orphansName)
+	}
+	t.adoptOrphans(breakers)
+}
+
+// orphansName names the node of the processes without a usable parent
+const orphansName = "(orphans)"
+
+// adoptOrphans moves processes under the "(orphans)" node, which is added
+// below init when there is one
+func (t *Tree) adoptOrphans(idxs []int) {
+	if len(idxs) == 0 {
+		return
+	}
+	pid := 0
+	for _, p := range t.procs {
+		if p.Group && p.Cmd == orphansName {
+			pid = p.PID
+		}
+	}
+	if pid == 0 {
+		orphans := Process{
+			PID:       t.nextGroupPID(),
+			Cmd:       orphansName,
+			Group:     true,
+			ParentIdx: -1,
+			ChildIdx:  -1,
+			SisterIdx: -1,
+		}
+		if t.getPidIndex(1) != -1 {
+			orphans.PPID = 1
+		}
+		pid = orphans.PID
+		t.procs = append(t.procs, orphans)
+		t.indexProcesses()
+	}
+	for _, idx := range idxs {
+		t.procs[idx].PPID = pid
+	}
+}
+
+// reconcileParents handles the processes whose parent is missing from
+// the snapshot, as it exited while the table was read. They are read
+// again up to --retries times to learn the parent they were reparented
+// to, those still without one go under the "(orphans)" node
+func (t *Tree) reconcileParents() {
+	lost := func() []int {
+		var idxs []int
+		for i, p := range t.procs {
+			if p.PPID != 0 && p.PPID != p.PID && t.getPidIndex(p.PPID) == -1 {
+				idxs = append(idxs, i)
+			}
+		}
+		return idxs
+	}
+
+	orphans := lost()
+	if resolveSourceName(t.config.Source) == "proc" {
+		for try := 0; try < t.config.Retries && len(orphans) > 0; try++ {
+			vanished := map[int]bool{}
+			for _, idx := range orphans {
+				procDir := filepath.Join("/proc", strconv.Itoa(t.procs[idx].PID))
+				if proc, ok := t.readProcLinux(procDir); ok {
+					t.procs[idx] = proc
+					t.procCache[procDir] = proc
+				} else {
+					vanished[t.procs[idx].PID] = true
+					delete(t.procCache, procDir)
+				}
+			}
+			if len(vanished) > 0 {
+				t.procs = slices.DeleteFunc(t.procs, func(p Process) bool { return vanished[p.PID] })
+				t.indexProcesses()
+			}
+			orphans = lost()
+		}
+	}
+
+	for _, idx := range orphans {
+		log.Debugf("pid %d: parent pid %d is gone, moved under %s", t.procs[idx].PID, t.procs[idx].PPID, orphansName)
+	}
+	t.adoptOrphans(orphans)
+}
+
+// makeTreeHierarchy builds the process hierarchy
+func (t *Tree) makeTreeHierarchy() {
+	// last child of each process, so appending a sister doesn't walk
+	// the whole list of children
+	lastChild := make([]int, len(t.procs))
+	for i := range t.procs {
+		parentIdx := t.getPidIndex(t.procs[i].PPID)
+		if parentIdx != i && parentIdx != -1 {
+
+			// set the parent idx of the current process
+			t.procs[i].ParentIdx = parentIdx
+
+			// if the parent has no children, point it to the current
+			parent := &t.procs[parentIdx]
+
+			if parent.ChildIdx == -1 {
+				parent.ChildIdx = i
+			} else {
+				t.procs[lastChild[parentIdx]].SisterIdx = i
+			}
+			lastChild[parentIdx] = i
+		}
+	}
+}
+
+// computeBirthOrder numbers the children of every process by start time,
+// falling back to PID order when start times are not available
+func (t *Tree) computeBirthOrder() {
+	for i := range t.procs {
+		var children []int
+		for child := t.procs[i].ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+			children = append(children, child)
+		}
+		sort.SliceStable(children, func(a, b int) bool {
+			pa, pb := t.procs[children[a]], t.procs[children[b]]
+			if pa.StartTime != pb.StartTime {
+				return pa.StartTime < pb.StartTime
+			}
+			return pa.PID < pb.PID
+		})
+		for n, child := range children {
+			t.procs[child].BirthOrder = n + 1
+		}
+	}
+}
+
+// countDescendants fills in the children and descendants counts of every
+// process in a single bottom-up pass over a breadth first ordering
+func (t *Tree) countDescendants() {
+	var order []int
+	for i := range t.procs {
+		if t.procs[i].ParentIdx == -1 {
+			order = append(order, t.subtreeIndices(i)...)
+		}
+	}
+
+	for n := len(order) - 1; n >= 0; n-- {
+		process := t.procs[order[n]]
+		if parent := process.ParentIdx; parent != -1 {
+			t.procs[parent].Children++
+			t.procs[parent].Descendants += 1 + process.Descendants
+		}
+	}
+}
+
+// accumulateSubtrees sums the cpu and memory usage of each process and
+// its descendants into its Subtree fields
+func (t *Tree) accumulateSubtrees() {
+	var order []int
+	for i := range t.procs {
+		process := &t.procs[i]
+		process.SubtreeCPU = process.CPUPercent
+		process.SubtreeRSS = process.RSS
+		process.SubtreeVSZ = process.VSZ
+		process.SubtreeSwap = process.Swap
+		if process.ParentIdx == -1 {
+			order = append(order, t.subtreeIndices(i)...)
+		}
+	}
+
+	for n := len(order) - 1; n >= 0; n-- {
+		process := t.procs[order[n]]
+		if parent := process.ParentIdx; parent != -1 && !process.Thread {
+			t.procs[parent].SubtreeCPU += process.SubtreeCPU
+			t.procs[parent].SubtreeRSS += process.SubtreeRSS
+			t.procs[parent].SubtreeVSZ += process.SubtreeVSZ
+			t.procs[parent].SubtreeSwap += process.SubtreeSwap
+		}
+	}
+}
+
+// markChildren recursively marks children for printing
+func (t *Tree) markChildren(idx int) {
+	t.markDescendants(idx, -1)
+}
+
+// markDescendants marks idx and its descendants down to depth levels
+// below it, all of them when depth is negative
+func (t *Tree) markDescendants(idx int, depth int) {
+	// breadth first, level by level
+	level := []int{idx}
+	for ; len(level) > 0; depth-- {
+		var next []int
+		for _, i := range level {
+			t.procs[i].Print = true
+			if depth == 0 {
+				continue
+			}
+			for child := t.procs[i].ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+}
+
+// markProcs marks processes for printing based on criteria
+func (t *Tree) markProcs() {
+	if t.config.ShowParents != -1 {
+		t.markParents(t.config.ShowParents)
+		return
+	}
+	for i := range t.procs {
+		process := &t.procs[i]
+		if t.config.AOption {
+			process.Print = true
+		} else {
+			shouldPrintBranch := false
+
+			// Check various criteria
+			if ownedBy(*process, t.searchUsers) {
+				shouldPrintBranch = true
+			}
+			if t.config.UOption && process.Owner != "root" && process.Owner != t.ownerName(0) {
+				shouldPrintBranch = true
+			}
+			if slices.Contains(t.config.SearchPids, process.PID) {
+				shouldPrintBranch = true
+			}
+			if len(t.config.SearchStrs) > 0 && t.matchesSearch(t.matchText(*process)) && process.PID != myPID {
+				shouldPrintBranch = true
+			}
+
+			if shouldPrintBranch {
+				// Mark the branch for printing, --context levels of it
+				parent := process.ParentIdx
+				for depth := 0; parent != -1 && (t.config.Context < 0 || depth < t.config.Context); depth++ {
+					t.procs[parent].Print = true
+					parent = t.procs[parent].ParentIdx
+				}
+				// Mark children
+				t.markDescendants(i, t.config.Context)
+			}
+		}
+	}
+}
+
+// markParents marks the chain from the top process down to pid, and all
+// descendants of pid
+func (t *Tree) markParents(pid int) {
+	idx := t.getPidIndex(pid)
+	if idx == -1 {
+		return
+	}
+	for parent := t.procs[idx].ParentIdx; parent != -1; parent = t.procs[parent].ParentIdx {
+		t.procs[parent].Print = true
+	}
+	t.markChildren(idx)
+}
+
+// narrowMarked keeps of the marked processes those passing all of
+// --states, --jail, --zone, --group, --tty, --session, --min-cpu,
+// --min-rss, --min-fds and --where, and the ancestors leading to them
+func (t *Tree) narrowMarked() {
+	if len(t.config.States) == 0 && t.config.Jail == "" && t.config.Zone == "" && t.config.Group == "" && t.config.TTY == "" && t.config.Session == -1 &&
+		t.config.MinCPU <= 0 && t.minRSS == 0 && t.config.MinFDs <= 0 && t.whereFilter == nil {
+		return
+	}
+
+	var matching []int
+	for i := range t.procs {
+		if t.procs[i].Print && t.passesFilters(&t.procs[i]) {
+			matching = append(matching, i)
+		}
+		t.procs[i].Print = false
+	}
+	for _, i := range matching {
+		for idx := i; idx != -1 && !t.procs[idx].Print; idx = t.procs[idx].ParentIdx {
+			t.procs[idx].Print = true
+		}
+	}
+}
+
+// passesFilters reports whether a process passes the process filters
+// narrowMarked applies
+func (t *Tree) passesFilters(process *Process) bool {
+	switch {
+	case len(t.config.States) > 0 && !slices.Contains(t.config.States, process.State):
+	case t.config.Jail != "" && !isolationMatches(t.config.Jail, process.Jail, t.jailNames()):
+	case t.config.Zone != "" && !isolationMatches(t.config.Zone, process.Zone, t.zoneNames()):
+	case t.config.Group != "" && !slices.Contains(process.GIDs, t.searchGID):
+	case t.config.TTY != "" && process.TTY != strings.TrimPrefix(t.config.TTY, "/dev/"):
+	case t.config.Session != -1 && process.SID != t.config.Session:
+	case t.config.MinCPU > 0 && process.CPUPercent < t.config.MinCPU:
+	case t.minRSS > 0 && process.RSS < t.minRSS:
+	case t.config.MinFDs > 0 && process.FDs < t.config.MinFDs:
+	case t.whereFilter != nil && (process.Group || process.PID == myPID || !t.whereFilter(process)):
+	default:
+		return true
+	}
+	return false
+}
+
+// dropProcs removes processes that won't be printed from the tree structure
+func (t *Tree) dropProcs() {
+	for i := range t.procs {
+		process := &t.procs[i]
+		if process.Print {
+			// Drop children that won't print
+			child := process.ChildIdx
+			for child != -1 && !t.procs[child].Print {
+				child = t.procs[child].SisterIdx
+			}
+			process.ChildIdx = child
+
+			// Drop sisters that won't print
+			tmp := process.SisterIdx
+			for tmp != -1 && !t.procs[tmp].Print {
+				tmp = t.procs[tmp].SisterIdx
+			}
+			process.SisterIdx = tmp
+		}
+	}
+}
+
+// loadProcesses takes a snapshot of the process table from the source
+// selected with --source
+func (t *Tree) loadProcesses() error {
+	t.cpuQuotaCache = map[string]string{}
+	t.cgroupStatsCache = map[string]string{}
+	t.listenSockets = nil
+	// accounts may have been renamed since the last snapshot
+	t.ownerNamesMu.Lock()
+	t.ownerNames = map[int]string{}
+	t.ownerNamesMu.Unlock()
+
+	source, err := t.openSource(t.config.Source)
+	if err != nil {
+		return err
+	}
+	list, err := source.Processes()
+	if err != nil {
+		return err
+	}
+
+	t.procs = list
+	t.prepareProcesses()
+	return nil
+}
+
+// prepareProcesses applies the options that drop or add processes to a
+// freshly loaded table
+func (t *Tree) prepareProcesses() {
+	if t.config.HideKernel {
+		// kthreadd and its children, i.e. the whole kernel subtree
+		t.procs = slices.DeleteFunc(t.procs, func(p Process) bool { return p.Kernel })
+	}
+	t.indexProcesses()
+	t.reconcileParents()
+	t.breakCycles()
+
+	if t.config.Threads {
+		t.addThreads()
+	}
+	if t.config.Namespace != "" {
+		t.groupByNamespace(t.config.Namespace)
+	}
+	if t.config.GroupJails {
+		t.groupByJail()
+	}
+	if t.config.GroupUnits {
+		t.groupByUnit()
+	}
+	if t.config.GroupPods {
+		t.groupByPod()
+	}
+	if t.config.GroupContainers {
+		t.groupByContainer()
+	}
+}
+
+// linuxTTYName names the terminal device number of /proc/PID/stat's
+// tty_nr, e.g. pts/3 or tty1, "" for none
+func linuxTTYName(ttyNr uint64) string {
+	major := (ttyNr >> 8) & 0xfff
+	minor := (ttyNr & 0xff) | ((ttyNr >> 12) & 0xfff00)
+	switch {
+	case ttyNr == 0:
+		return ""
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	}
+	return fmt.Sprintf("%d,%d", major, minor)
+}
+
+// statusInts extracts the numbers of a line of /proc/PID/status, e.g.
+// NSpid, the pid of the process in each nested pid namespace from the
+// outermost in
+func statusInts(status, key string) []int {
+	for _, line := range strings.Split(status, "\n") {
+		value, found := strings.CutPrefix(line, key+":")
+		if !found {
+			continue
+		}
+		var ints []int
+		for _, field := range strings.Fields(value) {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil
+			}
+			ints = append(ints, n)
+		}
+		return ints
+	}
+	return nil
+}
+
+func stripPath(path string) string {
+
+	//strip long paths
+	lastSlash := strings.LastIndex(path, "/")
+	if lastSlash != -1 {
+		return path[lastSlash+1:] // Everything after the last slash
+	}
+	return path
+}
+
+// getProcessesLinux reads processes directly from /proc filesystem (Linux)
+func (t *Tree) getProcessesLinux() ([]Process, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("direct process reading only supported on Linux")
+	}
+
+	return t.readProcRoot("/proc")
+}
+
+// readProcRoot reads the PID directories of a /proc layout
+func (t *Tree) readProcRoot(root string) ([]Process, error) {
+	procDirs, err := filepath.Glob(filepath.Join(root, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	read := t.readProcDirs(procDirs)
+
+	list := make([]Process, 0, len(procDirs))
+	t.procCache = make(map[string]Process, len(procDirs))
+	for i, procDir := range procDirs {
+		if read[i].ok {
+			t.procCache[procDir] = read[i].proc
+			list = append(list, read[i].proc)
+		}
+	}
+
+	return list, nil
+}
+
+// procResult is the outcome of readProcLinux for one directory
+type procResult struct {
+	proc Process
+	ok   bool
+}
+
+// readProcDirs reads the /proc/PID directories with --jobs workers, the
+// results are in the order of procDirs whatever the order they finish in
+func (t *Tree) readProcDirs(procDirs []string) []procResult {
+	jobs := t.config.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	jobs = min(jobs, len(procDirs))
+
+	// fill the caches shared by the workers before they start
+	linuxBootTime()
+	if (t.config.Net || t.columnShown("ports")) && t.listenSockets == nil {
+		t.listenSockets = readListenSockets("/proc/net")
+	}
+
+	results := make([]procResult, len(procDirs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i].proc, results[i].ok = t.readProcLinux(procDirs[i])
+			}
+		}()
+	}
+	for i := range procDirs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// readProcLinux reads a single /proc/PID directory, ok is false when the
+// process vanished or could not be parsed
+func (t *Tree) readProcLinux(procDir string) (Process, bool) {
+	var proc Process
+
+	// Get UID from directory stat
+	if stat, err := os.Stat(procDir); err == nil {
+		if uid, ok := fileOwner(stat); ok {
+			proc.UID = uid
+			proc.Owner = t.ownerName(uid)
+		}
+	} else {
+		return proc, false // process vanished
+	}
+
+	// Read /proc/PID/stat
+	statPath := filepath.Join(procDir, "stat")
+	statData, err := os.ReadFile(statPath)
+	if err != nil {
+		return proc, false // process vanished
+	}
+
+	if !parseProcStat(string(statData), &proc) {
+		return proc, false
+	}
+
+	if t.config.OOM {
+		proc.OOMScore, proc.OOMScoreAdj, _ = readOOM(procDir)
+	}
+
+	if t.config.FDs || t.config.MinFDs > 0 || t.columnShown("fds") {
+		proc.FDs = countFDs(procDir)
+	}
+
+	if t.config.Cwd || t.columnShown("cwd") {
+		// both fail for the processes of other users unless root
+		proc.Cwd, _ = os.Readlink(filepath.Join(procDir, "cwd"))
+		proc.Root, _ = os.Readlink(filepath.Join(procDir, "root"))
+	}
+
+	if t.config.IO || t.columnShown("read") || t.columnShown("write") {
+		proc.ReadBytes, proc.WriteBytes, proc.IOKnown = readIO(procDir)
+	}
+
+	if t.config.Net || t.columnShown("ports") {
+		proc.Ports = t.listeningPorts(procDir)
+	}
+
+	if proc.ThreadCount < 1 {
+		proc.ThreadCount = 1
+	}
+
+	// Read /proc/PID/cmdline for full command, with --low-memory once
+	// the tree is pruned
+	var cmdline string
+	if !t.deferCmdlines() {
+		cmdline = readCmdline(procDir)
+	}
+	if cmdline != "" {
+		proc.Cmd = cmdline
+	} else if proc.PID == 2 || proc.PPID == 2 {
+		// kthreadd and the kernel threads it spawns have no command line,
+		// they are shown in brackets like ps does
+		proc.Kernel = true
+		proc.Cmd = "[" + proc.Cmd + "]"
+	}
+
+	if t.config.MatchField == "exe" || t.whereExe || t.config.Audit {
+		// fails for the processes of other users unless root
+		proc.Exe, _ = os.Readlink(filepath.Join(procDir, "exe"))
+	}
+
+	if t.config.ShowMem || t.columnShown("vsz") {
+		if vsz, rss, ok := readStatm(procDir); ok {
+			proc.VSZ, proc.RSS = vsz, rss
+		}
+	}
+
+	if t.config.NSPids || t.config.Group != "" || t.config.ShowSwap || t.config.Audit || t.config.Caps || t.columnShown("caps") {
+		if statusData, err := os.ReadFile(filepath.Join(procDir, "status")); err == nil {
+			status := string(statusData)
+			if t.config.Audit {
+				auditProcess(&proc, procDir, status)
+			}
+			proc.Swap = statusBytes(status, "VmSwap")
+			proc.CapEff = statusCaps(status, "CapEff")
+			proc.NSPids = statusInts(status, "NSpid")
+			// the real gid, then the supplementary groups
+			if gids := statusInts(status, "Gid"); len(gids) > 0 {
+				proc.GIDs = append(gids[:1], statusInts(status, "Groups")...)
+			}
+		}
+	}
+
+	// Read /proc/PID/cgroup for cgroup membership
+	if cgroupData, err := os.ReadFile(filepath.Join(procDir, "cgroup")); err == nil {
+		proc.Cgroups = parseCgroupFile(string(cgroupData))
+		proc.Container = containerID(proc.Cgroups)
+		proc.Pod = podUID(proc.Cgroups)
+	}
+
+	proc.ParentIdx = -1
+	proc.ChildIdx = -1
+	proc.SisterIdx = -1
+	proc.Print = false
+
+	return proc, true
+}
+
+// parseProcStat parses the content of /proc/PID/stat into proc, it
+// reports false when the pid can't be read
+func parseProcStat(data string, proc *Process) bool {
+	// the command name may hold spaces and parentheses, e.g. (sd-pam) or
+	// "tmux: server", it runs from the first ( to the last )
+	open := strings.IndexByte(data, '(')
+	end := strings.LastIndexByte(data, ')')
+	if open == -1 || end < open {
+		return false
+	}
+	statFields := append([]string{strings.TrimSpace(data[:open]), data[open+1 : end]}, strings.Fields(data[end+1:])...)
+	if len(statFields) < 5 {
+		return false
+	}
+
+	if pid, err := strconv.Atoi(statFields[0]); err == nil {
+		proc.PID = pid
+	} else {
+		return false
+	}
+
+	proc.Comm = statFields[1]
+	proc.Cmd = proc.Comm
+	proc.State = statFields[2]
+	if len(statFields) > 6 {
+		proc.SID, _ = strconv.Atoi(statFields[5])
+		if ttyNr, err := strconv.ParseUint(statFields[6], 10, 32); err == nil {
+			proc.TTY = linuxTTYName(ttyNr)
+		}
+	}
+
+	if ppid, err := strconv.Atoi(statFields[3]); err == nil {
+		proc.PPID = ppid
+	}
+
+	if pgid, err := strconv.Atoi(statFields[4]); err == nil {
+		proc.PGID = pgid
+	}
+
+	if len(statFields) > 23 {
+		utime, _ := strconv.ParseUint(statFields[13], 10, 64)
+		stime, _ := strconv.ParseUint(statFields[14], 10, 64)
+		proc.CPUTicks = utime + stime
+
+		// num_threads, the same count as Threads: in /proc/PID/status
+		if threads, err := strconv.Atoi(statFields[19]); err == nil {
+			proc.ThreadCount = threads
+		}
+
+		if start, err := strconv.ParseUint(statFields[21], 10, 64); err == nil {
+			proc.StartTime = start
+			proc.Started = linuxStarted(start)
+		}
+
+		if rss, err := strconv.ParseUint(statFields[23], 10, 64); err == nil {
+			proc.RSS = rss * uint64(os.Getpagesize())
+		}
+
+		proc.Priority, _ = strconv.Atoi(statFields[17])
+		proc.Nice, _ = strconv.Atoi(statFields[18])
+	}
+
+	// rt_priority and policy, also in /proc/PID/sched
+	if len(statFields) > 40 {
+		proc.RTPriority, _ = strconv.Atoi(statFields[39])
+		if policy, err := strconv.Atoi(statFields[40]); err == nil {
+			proc.Policy = schedPolicies[policy]
+		}
+	}
+
+	return true
+}
+
+// getProcesses reads processes using ps command
+func (t *Tree) getProcesses() ([]Process, error) {
+	var cmd *exec.Cmd
+	var scanner *bufio.Scanner
+
+	// Use ps command based on OS
+	var psCmd []string
+	switch runtime.GOOS {
+	case "linux":
+		psCmd = []string{"ps", "-eo", "uid,pid,ppid,pgid,args"}
+	case "darwin", "freebsd", "netbsd", "openbsd":
+		psCmd = []string{"ps", "-axwwo", "user,pid,ppid,pgid,wq,comm"}
+	case "aix":
+		psCmd = []string{"ps", "-eko", "uid,pid,ppid,pgid,thcount,args"}
+	default:
+		psCmd = []string{"ps", "-ef"}
+	}
+
+	cmd = exec.Command(psCmd[0], psCmd[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	defer cmd.Wait()
+
+	scanner = bufio.NewScanner(stdout)
+
+	list := make([]Process, 0)
+
+	// Skip header line
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("no input")
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+
+		var proc Process
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		// Parse based on OS and ps format
+		switch runtime.GOOS {
+		case "linux", "aix":
+			if uid, err := strconv.Atoi(fields[0]); err == nil {
+				proc.UID = uid
+				proc.Owner = t.ownerName(uid)
+			}
+			if pid, err := strconv.Atoi(fields[1]); err == nil {
+				proc.PID = pid
+			}
+			if ppid, err := strconv.Atoi(fields[2]); err == nil {
+				proc.PPID = ppid
+			}
+			if pgid, err := strconv.Atoi(fields[3]); err == nil {
+				proc.PGID = pgid
+			}
+			if len(fields) > 4 {
+				if runtime.GOOS == "aix" && len(fields) > 5 {
+					if thcount, err := strconv.Atoi(fields[4]); err == nil {
+						proc.ThreadCount = thcount
+					}
+					proc.Cmd = strings.Join(fields[5:], " ")
+				} else {
+					proc.ThreadCount = 1
+					proc.Cmd = strings.Join(fields[4:], " ")
+				}
+			}
+		case "freebsd", "netbsd", "openbsd":
+			proc.Owner = fields[0]
+			if pid, err := strconv.Atoi(fields[1]); err == nil {
+				proc.PID = pid
+			}
+			if ppid, err := strconv.Atoi(fields[2]); err == nil {
+				proc.PPID = ppid
+			}
+			if pgid, err := strconv.Atoi(fields[3]); err == nil {
+				proc.PGID = pgid
+			}
+			if len(fields) > 4 {
+				proc.Cmd = strings.Join(fields[4:], " ")
+			}
+			proc.ThreadCount = 1
+		case "darwin":
+			proc.Owner = fields[0]
+			if pid, err := strconv.Atoi(fields[1]); err == nil {
+				proc.PID = pid
+			}
+			if ppid, err := strconv.Atoi(fields[2]); err == nil {
+				proc.PPID = ppid
+			}
+			if pgid, err := strconv.Atoi(fields[3]); err == nil {
+				proc.PGID = pgid
+			}
+
+			if len(fields) > 4 {
+
+				if len(fields) > 5 {
+					if thcount, err := strconv.Atoi(fields[4]); err == nil {
+						proc.ThreadCount = thcount
+					}
+					proc.Cmd = fields[5]
+				} else {
+					proc.ThreadCount = 1
+					proc.Cmd = fields[4]
+				}
+
+				proc.Cmd = stripPath(proc.Cmd)
+
+			}
+		default:
+			// Default ps -ef format
+			proc.Owner = fields[0]
+			if pid, err := strconv.Atoi(fields[1]); err == nil {
+				proc.PID = pid
+			}
+			if ppid, err := strconv.Atoi(fields[2]); err == nil {
+				proc.PPID = ppid
+			}
+			if len(fields) > 7 {
+				proc.Cmd = strings.Join(fields[7:], " ")
+			}
+			proc.ThreadCount = 1
+		}
+
+		proc.ParentIdx = -1
+		proc.ChildIdx = -1
+		proc.SisterIdx = -1
+		proc.Print = false
+
+		list = append(list, proc)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (t *Tree) debugPrintProcs(enforcePrintFlag bool) {
+	if t.config.DOption {
+		var (
+			purple    = lipgloss.Color("99")
+			gray      = lipgloss.Color("245")
+			lightGray = lipgloss.Color("241")
+
+			headerStyle  = lipgloss.NewStyle().Foreground(purple).Bold(true).Align(lipgloss.Center)
+			cellStyle    = lipgloss.NewStyle().Padding(0, 1)
+			oddRowStyle  = cellStyle.Foreground(gray)
+			evenRowStyle = cellStyle.Foreground(lightGray)
+		)
+
+		tbl := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(lipgloss.NewStyle().Foreground(purple)).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				switch {
+				case row == table.HeaderRow:
+					return headerStyle
+				case row%2 == 0:
+					return evenRowStyle
+				default:
+					return oddRowStyle
+				}
+			}).
+			Headers("idx", "parentIdx", "childIdx", "sisterIdx", "PID", "PPID", "PGID", "PROCESS")
+
+		for i := range t.procs {
+			p := t.procs[i]
+			if enforcePrintFlag {
+				if p.Print {
+					tbl.Row(strconv.Itoa(i), strconv.Itoa(p.ParentIdx), strconv.Itoa(p.ChildIdx), strconv.Itoa(p.SisterIdx), strconv.Itoa(p.PID), strconv.Itoa(p.PPID), strconv.Itoa(p.PGID), p.Cmd)
+				}
+			} else {
+				tbl.Row(strconv.Itoa(i), strconv.Itoa(p.ParentIdx), strconv.Itoa(p.ChildIdx), strconv.Itoa(p.SisterIdx), strconv.Itoa(p.PID), strconv.Itoa(p.PPID), strconv.Itoa(p.PGID), p.Cmd)
+			}
+		}
+		log.Debug(tbl)
+	}
+}
@@ -0,0 +1,674 @@
+package pstree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// trickyComms are command names that break a stat parser splitting on
+// spaces or trimming parentheses
+var trickyComms = []string{
+	"bash",
+	"(sd-pam)",
+	"tmux: server",
+	"a) (b",
+	") S 1 2 3",
+	"((",
+	"))",
+	"x  y",
+	"",
+}
+
+// statLine formats a /proc/PID/stat line of a sleeping process
+func statLine(pid int, comm string, ppid, pgid int) string {
+	return fmt.Sprintf("%d (%s) S %d %d %d 34816 -1 4194560 100 0 0 0 12 5 0 0 20 0 3 0 61200 1000 250 "+
+		"18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n", pid, comm, ppid, pgid, pgid)
+}
+
+func TestParseProcStat(t *testing.T) {
+	for _, comm := range trickyComms {
+		var proc Process
+		if !parseProcStat(statLine(1300, comm, 1211, 1300), &proc) {
+			t.Errorf("comm %q: not parsed", comm)
+			continue
+		}
+		if proc.PID != 1300 || proc.Comm != comm || proc.PPID != 1211 || proc.PGID != 1300 || proc.SID != 1300 {
+			t.Errorf("comm %q: parsed as pid %d comm %q ppid %d pgid %d sid %d", comm, proc.PID, proc.Comm, proc.PPID, proc.PGID, proc.SID)
+		}
+		if proc.State != "S" || proc.TTY != "pts/0" || proc.ThreadCount != 3 || proc.StartTime != 61200 || proc.CPUTicks != 17 {
+			t.Errorf("comm %q: parsed as state %q tty %q threads %d start %d ticks %d", comm, proc.State, proc.TTY, proc.ThreadCount, proc.StartTime, proc.CPUTicks)
+		}
+	}
+
+	for _, data := range []string{"", "1300", "1300 sh S 1 2", "1300 (sh S 1 2", "x (sh) S 1 2"} {
+		var proc Process
+		if parseProcStat(data, &proc) {
+			t.Errorf("%q parsed, want an error", data)
+		}
+	}
+}
+
+// tableTree indexes a process table like loadProcesses does, the tree
+// is left to build
+func tableTree(procs []Process) *Tree {
+	tr := newTree()
+	tr.procs = procs
+	tr.indexProcesses()
+	tr.resetTree()
+	return tr
+}
+
+func TestCompileWhere(t *testing.T) {
+	process := Process{PID: 42, PPID: 1, UID: 33, Owner: "www-data", Comm: "php-fpm", Cmd: `php-fpm: pool "www"`, State: "S", RSS: 300 << 20, CPUPercent: 12.5}
+	for _, tc := range []struct {
+		expr       string
+		ignoreCase bool
+		want       bool
+		err        string
+	}{
+		{expr: `user == "www-data"`, want: true},
+		{expr: `user != "www-data"`, want: false},
+		{expr: `user == www-data`, want: true},
+		{expr: `user != josé`, want: true},
+		{expr: `comm != Владимир`, want: true},
+		{expr: `pid == 42`, want: true},
+		{expr: `pid > 42`, want: false},
+		{expr: `ppid < 2 && uid >= 33`, want: true},
+		{expr: `cpu >= 12.5%`, want: true},
+		{expr: `cpu > 50`, want: false},
+		{expr: `rss > 200MB`, want: true},
+		{expr: `rss <= 300Mi`, want: true},
+		{expr: `rss > 1G`, want: false},
+		{expr: `rss > 314572800`, want: false},
+		{expr: `cmd =~ "^php-fpm: pool"`, want: true},
+		{expr: `cmd =~ "^PHP"`, want: false},
+		{expr: `cmd =~ "^PHP"`, ignoreCase: true, want: true},
+		{expr: `cmd !~ "nginx"`, want: true},
+		{expr: `cmd == "php-fpm: pool \"www\""`, want: true},
+		{expr: `cmd == 'php-fpm: pool "www"'`, want: true},
+		{expr: `cmd == 'php-fpm: pool \"www\"'`, want: true},
+		{expr: `comm == 'php\x2dfpm'`, want: true},
+		// && binds tighter than ||
+		{expr: `user == "root" && pid == 1 || pid == 42`, want: true},
+		{expr: `pid == 42 || user == "root" && pid == 1`, want: true},
+		{expr: `(pid == 42 || user == "root") && pid == 1`, want: false},
+		{expr: `! pid == 1`, want: true},
+		{expr: `!(pid == 42 || pid == 1)`, want: false},
+		{expr: `!!(pid == 42)`, want: true},
+		{expr: `((pid == 42))`, want: true},
+
+		{expr: `user == "www-data`, err: "unterminated string"},
+		{expr: `cmd == "\q"`, err: "invalid string"},
+		{expr: `user < "x"`, err: "user is not numeric"},
+		{expr: `pid =~ "4"`, err: "pid cannot be matched with =~"},
+		{expr: `name == "x"`, err: `unknown field "name"`},
+		{expr: `"user" == "x"`, err: "expected a field name"},
+		{expr: `pid 42`, err: "expected an operator after pid"},
+		{expr: `pid ==`, err: "expected a value after pid =="},
+		{expr: `(pid == 42`, err: "missing )"},
+		{expr: `pid == 42)`, err: `unexpected ")"`},
+		{expr: `pid == 1 $`, err: `unexpected '$'`},
+		{expr: `pid == 1 → 2`, err: `unexpected '→'`},
+		{expr: `pid == x`, err: `invalid number "x"`},
+		{expr: `rss > lots`, err: `invalid size "lots"`},
+		{expr: `cmd =~ "("`, err: "invalid pattern"},
+	} {
+		tr := newTree()
+		tr.config.Where = tc.expr
+		tr.config.IgnoreCase = tc.ignoreCase
+		err := tr.compileWhere()
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want %q", tc.expr, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tc.expr, err)
+		case tr.whereFilter(&process) != tc.want:
+			t.Errorf("%s: %t, want %t", tc.expr, !tc.want, tc.want)
+		}
+	}
+}
+
+func TestWhereStrings(t *testing.T) {
+	// single and double quotes take the same escapes
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{`"a\tb"`, "a\tb"},
+		{`'a\tb'`, "a\tb"},
+		{`"it's"`, "it's"},
+		{`'it\'s'`, "it's"},
+		{`'say "hi"'`, `say "hi"`},
+		{`'say \"hi\"'`, `say "hi"`},
+		{`'C:\\dir'`, `C:\dir`},
+		{`''`, ""},
+	} {
+		tokens, err := whereTokens(tc.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if len(tokens) != 1 || !tokens[0].quoted || tokens[0].text != tc.want {
+			t.Errorf("%s: tokens %+v, want the string %q", tc.expr, tokens, tc.want)
+		}
+	}
+}
+
+func TestSearchPatterns(t *testing.T) {
+	const cmd = "/usr/bin/Python3 -m http.server 8080"
+	for _, tc := range []struct {
+		patterns   []string
+		regex      bool
+		ignoreCase bool
+		want       bool
+		err        string
+	}{
+		{patterns: []string{"Python3"}, want: true},
+		{patterns: []string{"python3"}, want: false},
+		{patterns: []string{"python3"}, ignoreCase: true, want: true},
+		{patterns: []string{"nginx", "8080"}, want: true},
+		// without -e the pattern is a plain substring
+		{patterns: []string{"http.serv.r"}, want: false},
+		{patterns: []string{"p(ython"}, want: false},
+		{patterns: []string{"http.serv.r"}, regex: true, want: true},
+		{patterns: []string{"^/usr/.*8080$"}, regex: true, want: true},
+		{patterns: []string{"^python"}, regex: true, ignoreCase: true, want: false},
+		{patterns: []string{"/python[0-9]"}, regex: true, ignoreCase: true, want: true},
+		{patterns: []string{"p(ython"}, regex: true, err: "invalid pattern"},
+	} {
+		tr := newTree()
+		tr.config.SearchStrs = tc.patterns
+		tr.config.Regex = tc.regex
+		tr.config.IgnoreCase = tc.ignoreCase
+		err := tr.compileSearch()
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q regex %t: error %v, want %q", tc.patterns, tc.regex, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%q regex %t: %v", tc.patterns, tc.regex, err)
+		case tr.matchesSearch(cmd) != tc.want:
+			t.Errorf("%q regex %t ignore case %t: %t, want %t", tc.patterns, tc.regex, tc.ignoreCase, !tc.want, tc.want)
+		}
+	}
+}
+
+func TestBreakCycles(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// pid and parent pid of each process
+		table [][2]int
+		// the processes moved under (orphans)
+		moved []int
+	}{
+		{"none", [][2]int{{1, 0}, {2, 1}, {3, 2}}, nil},
+		{"self", [][2]int{{1, 0}, {5, 5}, {6, 5}}, []int{5}},
+		{"pair", [][2]int{{1, 0}, {11, 10}, {10, 11}, {12, 11}}, []int{10}},
+		{"ring", [][2]int{{1, 0}, {31, 32}, {32, 30}, {30, 31}, {33, 32}}, []int{30}},
+		{"two", [][2]int{{1, 0}, {10, 11}, {11, 10}, {20, 20}}, []int{10, 20}},
+		{"idle", [][2]int{{0, 0}, {1, 0}, {2, 1}}, nil},
+	} {
+		var procs []Process
+		for _, pids := range tc.table {
+			procs = append(procs, Process{PID: pids[0], PPID: pids[1], Cmd: fmt.Sprint("p", pids[0])})
+		}
+		tr := tableTree(procs)
+		tr.breakCycles()
+		tr.makeTreeHierarchy()
+
+		var moved []int
+		for _, process := range tr.procs {
+			if parent := tr.getPidIndex(process.PPID); parent != -1 && tr.procs[parent].Cmd == orphansName {
+				moved = append(moved, process.PID)
+			}
+			if process.Group && process.PPID != 1 {
+				t.Errorf("%s: %s below %d, want below init", tc.name, orphansName, process.PPID)
+			}
+		}
+		slices.Sort(moved)
+		if !slices.Equal(moved, tc.moved) {
+			t.Errorf("%s: moved %v, want %v", tc.name, moved, tc.moved)
+		}
+		for i, process := range tr.procs {
+			depth := 0
+			for idx := i; tr.procs[idx].ParentIdx != -1; idx = tr.procs[idx].ParentIdx {
+				if depth++; depth > len(tr.procs) {
+					t.Fatalf("%s: pid %d is still in a cycle", tc.name, process.PID)
+				}
+			}
+		}
+	}
+}
+
+func TestCompactSiblings(t *testing.T) {
+	sleep := func(pid int, owner, cmd string) Process {
+		return Process{PID: pid, PPID: 1, PGID: 1, Owner: owner, Comm: "sleep", Cmd: cmd, ThreadCount: 1}
+	}
+	thread := func(pid int, name string) Process {
+		return Process{PID: pid, PPID: 1, PGID: 1, Owner: "root", Cmd: "{" + name + "}", ThreadCount: 1, Thread: true}
+	}
+	for _, tc := range []struct {
+		name     string
+		children []Process
+		// the labels of the children of init once compacted
+		want []string
+	}{
+		{"single", []Process{sleep(2, "root", "sleep 30")}, []string{"00002 root sleep 30"}},
+		{"identical", []Process{sleep(2, "root", "sleep 30"), sleep(3, "root", "sleep 30"), sleep(4, "root", "sleep 30")}, []string{"3*[sleep]"}},
+		{"arguments", []Process{sleep(2, "root", "sleep 30"), sleep(3, "root", "sleep 60"), sleep(4, "root", "sleep 30")}, []string{"2*[sleep]", "00003 root sleep 60"}},
+		{"owners", []Process{sleep(2, "root", "sleep 30"), sleep(3, "nobody", "sleep 30")}, []string{"00002 root sleep 30", "00003 nobody sleep 30"}},
+		{"parent", []Process{sleep(2, "root", "sleep 30"), sleep(3, "root", "sleep 30"), {PID: 4, PPID: 2, Cmd: "child"}}, []string{"00002 root sleep 30", "00003 root sleep 30"}},
+		{"threads", []Process{thread(2, "worker"), thread(3, "worker"), thread(4, "io")}, []string{"2*[{worker}]", "00004 root {io}"}},
+	} {
+		tr := tableTree(append([]Process{{PID: 1, Owner: "root", Cmd: "init", ThreadCount: 1}}, tc.children...))
+		tr.makeTreeHierarchy()
+		for i := range tr.procs {
+			tr.procs[i].Print = true
+		}
+		tr.compactSiblings()
+
+		var got []string
+		for child := tr.procs[0].ChildIdx; child != -1; child = tr.procs[child].SisterIdx {
+			got = append(got, tr.nodeLabel(child))
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseEscalation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("only SIGKILL exists on Windows")
+	}
+	for _, tc := range []struct {
+		spec string
+		want string
+		err  string
+	}{
+		{spec: "KILL", want: "SIGKILL"},
+		{spec: "TERM:5s,KILL", want: "SIGTERM, then SIGKILL after 5s"},
+		{spec: "sigint:1s, 15:2s, 9", want: "SIGINT, then SIGTERM after 1s, then SIGKILL after 2s"},
+		{spec: "TERM:5s", want: "SIGTERM"},
+		{spec: "TERM,KILL", err: "needs a time to wait"},
+		{spec: "TERM:soon,KILL", err: `invalid wait "soon"`},
+		{spec: "TERM:-1s,KILL", err: `invalid wait "-1s"`},
+		{spec: "TERM:5s,NOPE", err: `unknown signal "NOPE"`},
+	} {
+		steps, err := parseEscalation(tc.spec)
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want %q", tc.spec, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tc.spec, err)
+		case describeSteps(steps) != tc.want:
+			t.Errorf("%s: %q, want %q", tc.spec, describeSteps(steps), tc.want)
+		}
+	}
+}
+
+func TestResolveSubtrees(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		roots   []int
+		targets []int
+		missing []int
+	}{
+		{[]string{"1211"}, []int{1211}, []int{1211, 1300, 1301, 1302, 1303}, nil},
+		// make is below bash, it adds nothing
+		{[]string{"make", "1211"}, []int{1211}, []int{1211, 1300, 1301, 1302, 1303}, nil},
+		{[]string{"make"}, []int{1301}, []int{1301, 1302, 1303}, nil},
+		{[]string{"cron", "postgres"}, []int{520, 900}, []int{520, 900, 901, 902}, nil},
+		{[]string{"nothing-runs-this"}, nil, nil, nil},
+		// no pid 15, though postgres has it in its command line
+		{[]string{"15"}, nil, nil, []int{15}},
+		{[]string{"4", "cron"}, []int{520}, []int{520}, []int{4}},
+	} {
+		tr := newTree()
+		tr.config.Source = "procfs:testdata/proc"
+		tr.config.NumericOwners = true
+		roots, targets, missing, err := tr.resolveSubtrees(tc.args, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pids := func(indices []int) []int {
+			var pids []int
+			for _, idx := range indices {
+				pids = append(pids, tr.procs[idx].PID)
+			}
+			return pids
+		}
+		if got := pids(roots); !slices.Equal(got, tc.roots) {
+			t.Errorf("%q: roots %v, want %v", tc.args, got, tc.roots)
+		}
+		if !slices.Equal(missing, tc.missing) {
+			t.Errorf("%q: missing %v, want %v", tc.args, missing, tc.missing)
+		}
+		got := pids(targets)
+		if !slices.Equal(slices.Sorted(slices.Values(got)), tc.targets) {
+			t.Errorf("%q: targets %v, want %v", tc.args, got, tc.targets)
+		}
+		// killSubtrees signals them backwards, children first
+		for i, idx := range targets {
+			if parent := tr.procs[idx].ParentIdx; slices.Contains(targets[i:], parent) {
+				t.Errorf("%q: %d comes before its parent %d", tc.args, tr.procs[idx].PID, tr.procs[parent].PID)
+			}
+		}
+	}
+}
+
+func TestKillSubtrees(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads the live /proc")
+	}
+	// start runs a shell command, reaped as soon as it exits
+	start := func(script string) int {
+		cmd := exec.Command("sh", "-c", script)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cmd.Process.Kill() })
+		go cmd.Wait()
+		return cmd.Process.Pid
+	}
+	// the shells exec the sleep once the trap is set
+	obeying := start("exec sleep 30")
+	ignoring := start("trap '' TERM; exec sleep 30")
+	reused := start("exec sleep 30")
+	time.Sleep(200 * time.Millisecond)
+
+	tr := newTree()
+	var out bytes.Buffer
+	tr.output = &out
+	if err := tr.loadProcesses(); err != nil {
+		t.Fatal(err)
+	}
+	var targets []int
+	for _, pid := range []int{obeying, ignoring, reused} {
+		idx := tr.getPidIndex(pid)
+		if idx == -1 {
+			t.Fatalf("pid %d is not in the process table", pid)
+		}
+		targets = append(targets, idx)
+	}
+	// as if the pid was given to another process since the snapshot
+	tr.procs[targets[2]].StartTime++
+
+	steps, err := parseEscalation("TERM:300ms,KILL:2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.killSubtrees(targets, steps, true); err != nil {
+		t.Fatal(err)
+	}
+	for pid, want := range map[int]string{
+		obeying:  "exited after SIGTERM",
+		ignoring: "exited after SIGKILL",
+		reused:   "already exited",
+	} {
+		if line := fmt.Sprintf("%d sleep: %s\n", pid, want); !strings.Contains(out.String(), line) {
+			t.Errorf("pid %d: want %q in\n%s", pid, line, out.String())
+		}
+	}
+	if !processAlive(reused) {
+		t.Errorf("pid %d was signaled though its start time differs", reused)
+	}
+}
+
+func TestColumnCell(t *testing.T) {
+	for _, tc := range []struct {
+		width int
+		value string
+		want  string
+	}{
+		{-8, "root", "root    "},
+		{-8, "postgres", "postgres"},
+		{-8, "longusername", "longuse+"},
+		{-8, "josé", "josé    "},
+		{6, "1234", "  1234"},
+		{6, "12345678", "12345+"},
+	} {
+		if got := columnCell(tc.width, tc.value); got != tc.want {
+			t.Errorf("%d %q: %q, want %q", tc.width, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  string
+	}{
+		{`notepad.exe`, `"notepad.exe"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{`echo %PATH%`, `"echo "^%"PATH"^%""`},
+		{`100%`, `"100"^%""`},
+	} {
+		if got := cmdQuote(tc.value); got != tc.want {
+			t.Errorf("%q: %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	const id = "3f1a9c0d5b7e2f4a6c8e0b1d3f5a7c9e1b3d5f7a9c0e2b4d6f8a0c2e4b6d8f0a"
+	for _, tc := range []struct {
+		name    string
+		cgroups map[string]string
+		want    string
+	}{
+		{"docker cgroupfs", map[string]string{"": "/docker/" + id}, id},
+		{"docker systemd", map[string]string{"": "/system.slice/docker-" + id + ".scope"}, id},
+		{"podman", map[string]string{"": "/machine.slice/libpod-" + id + ".scope/container"}, id},
+		{"containerd", map[string]string{"": "/default/" + id}, id},
+		{"containerd kubepods", map[string]string{"": "/kubepods/burstable/pod0f3e8b7a-1c2d-4e5f-8a9b-0c1d2e3f4a5b/" + id}, id},
+		{"cri-containerd", map[string]string{"": "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0f3e8b7a_1c2d.slice/cri-containerd-" + id + ".scope"}, id},
+		{"cri-o", map[string]string{"": "/kubepods.slice/kubepods-pod0f3e8b7a_1c2d.slice/crio-" + id + ".scope"}, id},
+		{"cgroup v1", map[string]string{"cpu,cpuacct": "/docker/" + id, "memory": "/docker/" + id}, id},
+		{"session scope", map[string]string{"": "/user.slice/user-1000.slice/session-2.scope"}, ""},
+		{"short id", map[string]string{"": "/system.slice/docker-3f1a9c0d5b7e.scope"}, ""},
+		{"root", map[string]string{"": "/"}, ""},
+		{"none", nil, ""},
+	} {
+		if got := containerID(tc.cgroups); got != tc.want {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPodUID(t *testing.T) {
+	const uid = "0f3e8b7a-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	for _, tc := range []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{"cgroupfs dashes", "/kubepods/burstable/pod" + uid + "/3f1a9c0d", uid},
+		{"cgroupfs guaranteed", "/kubepods/pod" + uid + "/3f1a9c0d", uid},
+		{"systemd underscores", "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0f3e8b7a_1c2d_4e5f_8a9b_0c1d2e3f4a5b.slice/cri-containerd-3f1a9c0d.scope", uid},
+		{"systemd guaranteed", "/kubepods.slice/kubepods-pod0f3e8b7a_1c2d_4e5f_8a9b_0c1d2e3f4a5b.slice/crio-3f1a9c0d.scope", uid},
+		{"no pod", "/kubepods.slice/kubepods-burstable.slice", ""},
+		{"not kubernetes", "/system.slice/pod" + uid + ".service", ""},
+	} {
+		if got := podUID(map[string]string{"": tc.cgroup}); got != tc.want {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReadListenSockets(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("the fixture holds addresses in little endian words")
+	}
+	got := readListenSockets(filepath.Join("testdata", "proc", "net"))
+	// the connected tcp and udp sockets 21003 and 21008 are left out
+	want := map[uint64]string{
+		21001: ":80",
+		21002: "127.0.0.1:5432",
+		21004: ":22",
+		21005: "[::1]:631",
+		21006: ":68/udp",
+		21007: "[::1]:53/udp",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+
+	for _, s := range []string{"", "0100007F", "0100007F:zz", "7F:0050", "0100007G:0050"} {
+		if address, ok := socketAddress(s); ok {
+			t.Errorf("%q decoded as %q, want an error", s, address)
+		}
+	}
+}
+
+func TestSplitOpts(t *testing.T) {
+	for _, tc := range []struct {
+		opts string
+		want []string
+		err  string
+	}{
+		{opts: "", want: nil},
+		{opts: "  -p\t-g 3\n", want: []string{"-p", "-g", "3"}},
+		{opts: `--exclude 'kworker/*' --where "user == 'root'"`, want: []string{"--exclude", "kworker/*", "--where", "user == 'root'"}},
+		{opts: `-o pid,"user"x ''`, want: []string{"-o", "pid,userx", ""}},
+		{opts: `a\ b c\"d 'e\f' "g\"h"`, want: []string{"a b", `c"d`, `e\f`, `g"h`}},
+		{opts: `--where 'user == root`, err: "unterminated ' quote"},
+		{opts: `--where "user`, err: `unterminated " quote`},
+		{opts: `-p \`, err: "trailing backslash"},
+	} {
+		got, err := splitOpts(tc.opts)
+		switch {
+		case tc.err != "":
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%q: error %v, want %q", tc.opts, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%q: %v", tc.opts, err)
+		case !slices.Equal(got, tc.want):
+			t.Errorf("%q: %q, want %q", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	// each flag is set by one source less than the one before
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("one: file\ntwo: file\nthree: file\nfour: file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PSTREE_OPTS", "--one opts --two 'from opts'")
+	t.Setenv("PSTREE_ONE", "env")
+	t.Setenv("PSTREE_TWO", "env")
+	t.Setenv("PSTREE_THREE", "env")
+
+	cmd := &cobra.Command{Use: "pstree"}
+	values := map[string]*string{}
+	for _, name := range []string{"one", "two", "three", "four", "five"} {
+		values[name] = cmd.Flags().String(name, "default", "")
+	}
+	if err := cmd.ParseFlags([]string{"--one", "flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(cmd, path); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"one":   "flag",
+		"two":   "from opts",
+		"three": "env",
+		"four":  "file",
+		"five":  "default",
+	} {
+		if got := *values[name]; got != want {
+			t.Errorf("--%s: %q, want %q", name, got, want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("six: file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(cmd, path); err == nil || !strings.Contains(err.Error(), `unknown flag "six"`) {
+		t.Errorf("unknown key: error %v", err)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("only SIGKILL exists on Windows")
+	}
+	for _, tc := range []struct {
+		spec string
+		sig  os.Signal
+		name string
+	}{
+		{"TERM", syscall.SIGTERM, "SIGTERM"},
+		{"SIGTERM", syscall.SIGTERM, "SIGTERM"},
+		{"hup", syscall.SIGHUP, "SIGHUP"},
+		{"sigint", syscall.SIGINT, "SIGINT"},
+		{"9", syscall.SIGKILL, "SIGKILL"},
+		{"15", syscall.SIGTERM, "SIGTERM"},
+	} {
+		sig, name, err := parseSignal(tc.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tc.spec, err)
+		} else if sig != tc.sig || name != tc.name {
+			t.Errorf("%s: %v %s, want %v %s", tc.spec, sig, name, tc.sig, tc.name)
+		}
+	}
+
+	for _, spec := range []string{"", "SIG", "NOPE", "SIGNOPE", "0", "-1", "1000", "9x", " TERM"} {
+		if sig, name, err := parseSignal(spec); err == nil {
+			t.Errorf("%q parsed as %v %s, want an error", spec, sig, name)
+		}
+	}
+}
+
+func TestApplyCustomGraphics(t *testing.T) {
+	saved := treeChars[GraphicsCustom]
+	t.Cleanup(func() { treeChars[GraphicsCustom] = saved })
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	apply := func(config string) error {
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return applyConfigFile(&cobra.Command{Use: "pstree"}, path)
+	}
+
+	if err := apply("custom-graphics:\n  leaf: '*'\n  last: '`-'\n"); err != nil {
+		t.Fatal(err)
+	}
+	if chars := treeChars[GraphicsCustom]; chars.S2 != "*" || chars.BarL != "`-" || chars.BarC != saved.BarC {
+		t.Errorf("custom glyphs leaf %q last %q branch %q", chars.S2, chars.BarL, chars.BarC)
+	}
+	if got := renderFixture(t, []string{procFixture, "-g", "custom", "1211"}); !strings.Contains(got, "`-*─ 01303") {
+		t.Errorf("the custom glyphs are not drawn:\n%s", got)
+	}
+
+	for config, want := range map[string]string{
+		"custom-graphics:\n  twig: '+'\n": `custom-graphics: unknown glyph "twig", expected some of bar, branch, last, leader, leaf, non-leader, parent`,
+		"custom-graphics: '+'\n":          "custom-graphics: expected names and glyphs",
+	} {
+		if err := apply(config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: error %v, want %q", config, err, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package pstree
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// userFilter is a -u or --not-user argument, with its uid when known
+type userFilter struct {
+	name string
+	uid  int
+}
+
+// ownerName returns the user name of a uid, or #uid when it has no passwd
+// entry, or the uid itself with --numeric-owners
+func (t *Tree) ownerName(uid int) string {
+	if t.config.NumericOwners {
+		return strconv.Itoa(uid)
+	}
+	t.ownerNamesMu.Lock()
+	name, ok := t.ownerNames[uid]
+	t.ownerNamesMu.Unlock()
+	if ok {
+		return name
+	}
+
+	// NSS may take a while, the other readers don't wait for it
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		name = u.Username
+	} else {
+		name = fmt.Sprintf("#%d", uid)
+	}
+	t.ownerNamesMu.Lock()
+	if t.ownerNames == nil {
+		t.ownerNames = map[int]string{}
+	}
+	t.ownerNames[uid] = name
+	t.ownerNamesMu.Unlock()
+	return name
+}
+
+// resolveUsers resolves user names and numeric uids. Names must exist,
+// uids are accepted as they are
+func (t *Tree) resolveUsers(specs []string) ([]userFilter, error) {
+	filters := make([]userFilter, 0, len(specs))
+	for _, spec := range specs {
+		if uid, err := strconv.Atoi(spec); err == nil {
+			filters = append(filters, userFilter{name: t.ownerName(uid), uid: uid})
+			continue
+		}
+		u, err := user.Lookup(spec)
+		if err != nil {
+			return nil, fmt.Errorf("user '%s' does not exist", spec)
+		}
+		// not a number on windows, where uids are SIDs
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			uid = -1
+		}
+		filters = append(filters, userFilter{name: spec, uid: uid})
+	}
+	return filters, nil
+}
+
+// ownedBy reports whether a process belongs to one of the users
+func ownedBy(process Process, users []userFilter) bool {
+	for _, u := range users {
+		if process.Owner == u.name || (u.uid != -1 && process.UID == u.uid && process.Owner != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeUsers unmarks the processes of the --not-user users, unless
+// they lead to other processes that still print
+func (t *Tree) excludeUsers() {
+	if len(t.excludedUsers) == 0 {
+		return
+	}
+
+	// children before their parents
+	var order []int
+	for i := range t.procs {
+		if t.procs[i].ParentIdx == -1 {
+			order = append(order, t.subtreeIndices(i)...)
+		}
+	}
+	for n := len(order) - 1; n >= 0; n-- {
+		process := &t.procs[order[n]]
+		if !process.Print || !ownedBy(*process, t.excludedUsers) {
+			continue
+		}
+		keep := false
+		for child := process.ChildIdx; child != -1; child = t.procs[child].SisterIdx {
+			keep = keep || t.procs[child].Print
+		}
+		process.Print = keep
+	}
+}
+
+// resolveGroup resolves a group name or numeric gid
+func resolveGroup(spec string) (int, error) {
+	if gid, err := strconv.Atoi(spec); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(spec)
+	if err != nil {
+		return 0, fmt.Errorf("group '%s' does not exist", spec)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group '%s' has no numeric gid", spec)
+	}
+	return gid, nil
+}
@@ -0,0 +1,88 @@
+package pstree
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func (t *Tree) newWaitCmd() *cobra.Command {
+	var timeout, interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "wait [--timeout 5m] pid|pattern ...",
+		Short: "Wait until processes and all their descendants have exited",
+		Long: `wait blocks until the processes given by pid or matching the patterns,
+and all their descendants, have exited, including the descendants that
+outlive their parent and get adopted elsewhere. On Linux pidfds tell the
+exits right away, the process table is read again every --interval for
+new children. It exits with status 124 when --timeout expires first, like
+timeout(1). Processes already gone, pids no longer running included, are
+no error.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateMatchField(t.config.MatchField); err != nil {
+				return &usageError{err}
+			}
+			if interval <= 0 {
+				return &usageError{fmt.Errorf("--interval must be positive")}
+			}
+			// pids already gone count as exited right away
+			_, targets, _, err := t.resolveSubtrees(args, true)
+			if err != nil {
+				return err
+			}
+
+			// the processes waited for by pid, with their start time to
+			// tell them from a new process reusing the pid
+			waiting := map[int]uint64{}
+			for _, idx := range targets {
+				waiting[t.procs[idx].PID] = t.procs[idx].StartTime
+			}
+			deadline := time.Now().Add(timeout)
+			for len(waiting) > 0 {
+				if timeout > 0 && !time.Now().Before(deadline) {
+					fmt.Fprintf(os.Stderr, "pstree: timed out, %d processes still running\n", len(waiting))
+					return &exitStatusError{Code: 124}
+				}
+				pause := interval
+				if timeout > 0 {
+					pause = min(pause, time.Until(deadline))
+				}
+				waitExit(slices.Sorted(maps.Keys(waiting)), pause)
+
+				if err := t.loadProcesses(); err != nil {
+					return err
+				}
+				t.makeTreeHierarchy()
+				running := map[int]uint64{}
+				for pid, start := range waiting {
+					idx := t.getPidIndex(pid)
+					if idx == -1 || t.procs[idx].StartTime != start || t.procs[idx].State == "Z" {
+						continue
+					}
+					for _, i := range t.subtreeIndices(idx) {
+						if t.procs[i].State != "Z" {
+							running[t.procs[i].PID] = t.procs[i].StartTime
+						}
+					}
+				}
+				waiting = running
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "give up after this long and exit with status 124, 0 waits forever")
+	cmd.Flags().DurationVar(&interval, "interval", time.Second, "time between reads of the process table")
+	cmd.Flags().BoolVarP(&t.config.Regex, "regex", "e", false, "match the patterns as regular expressions against command lines")
+	cmd.Flags().BoolVar(&t.config.IgnoreCase, "ignore-case", false, "match the patterns case-insensitively")
+	cmd.Flags().StringVar(&t.config.MatchField, "match-field", "cmdline", "what patterns are matched against: comm, exe or cmdline")
+
+	return cmd
+}
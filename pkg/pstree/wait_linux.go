@@ -0,0 +1,31 @@
+package pstree
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxPidfds bounds the pidfds opened by waitExit, the processes past it
+// are only noticed when the wait is over
+const maxPidfds = 256
+
+// waitExit sleeps for d, or until one of the processes exits, which their
+// pidfds tell right away
+func waitExit(pids []int, d time.Duration) {
+	var fds []unix.PollFd
+	for _, pid := range pids[:min(len(pids), maxPidfds)] {
+		fd, err := unix.PidfdOpen(pid, 0)
+		if err != nil {
+			continue
+		}
+		defer unix.Close(fd)
+		fds = append(fds, unix.PollFd{Fd: int32(fd), Events: unix.POLLIN})
+	}
+	if len(fds) == 0 {
+		// kernels before 5.3 have no pidfds
+		time.Sleep(d)
+		return
+	}
+	unix.Poll(fds, int(d.Milliseconds()))
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pstree
+
+import "time"
+
+// waitExit sleeps for d, the processes are polled without pidfds
+func waitExit(pids []int, d time.Duration) {
+	time.Sleep(d)
+}
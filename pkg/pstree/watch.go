@@ -0,0 +1,227 @@
+package pstree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// how often the fast strategy lists /proc, and the fastest redraw rate
+	fastPollInterval = 100 * time.Millisecond
+
+	// ANSI sequences used to redraw in place without clearing the screen
+	cursorHome    = "\033[H"
+	clearLine     = "\033[K"
+	clearScreen   = "\033[2J"
+	clearToEnd    = "\033[J"
+	hideCursor    = "\033[?25l"
+	showCursor    = "\033[?25h"
+	defaultPeriod = 2 * time.Second
+)
+
+func (t *Tree) newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [flags] [pid ...]",
+		Short: "Redraw the process tree periodically",
+		Long: `watch reprints the process tree every interval, like 'watch pstree' but
+redrawing in place from the top left corner to avoid flicker. All the
+filtering and display flags of pstree are honored.`,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completePids,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := t.setupConfig(args); err != nil {
+				return &usageError{err}
+			}
+			return t.watchTree(args)
+		},
+	}
+
+	t.addTreeFlags(cmd)
+	t.addWatchFlags(cmd)
+
+	return cmd
+}
+
+// addWatchFlags registers the flags controlling periodic refresh
+func (t *Tree) addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&t.config.Interval, "interval", defaultPeriod, "time between refreshes")
+	cmd.Flags().StringVar(&t.config.WatchStrategy, "watch-strategy", "poll", "how changes are noticed: poll (every interval), fast (cheap /proc listing) or netlink (proc connector)")
+	cmd.Flags().IntVar(&t.config.FollowPid, "follow", -1, "redraw the subtree of PID until it exits, then exit with its status")
+	cmd.Flags().StringVar(&t.config.OnNew, "on-new", "", "run this shell command for every process appearing in the tree, e.g. 'notify-send {name} {}'")
+	cmd.Flags().StringVar(&t.config.OnExit, "on-exit", "", "run this shell command for every process leaving the tree, {} is its pid, {ppid}, {user}, {name} and {cmd} the rest")
+	cmd.RegisterFlagCompletionFunc("follow", completePids)
+}
+
+// watchTree redraws the tree every config.Interval until interrupted, or
+// until the followed process exits
+func (t *Tree) watchTree(args []string) error {
+	if t.config.Interval <= 0 {
+		return &usageError{fmt.Errorf("--interval must be positive")}
+	}
+	if t.config.FollowPid != -1 {
+		t.config.SearchPids = []int{t.config.FollowPid}
+		t.config.SearchStrs = nil
+	}
+	if !t.config.DOption {
+		// informational logging would scribble over the redrawn screen
+		log.SetLevel(log.WarnLevel)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Print(hideCursor + clearScreen)
+	defer fmt.Print(showCursor)
+
+	changes, err := churnNotifier(ctx, t.config.WatchStrategy)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	var churn churnStats
+	var io ioRates
+	var hooks watchHooks
+	lazy := false
+	for {
+		header := fmt.Sprintf("Every %s: %s    %s", t.config.Interval, strings.Join(os.Args, " "), time.Now().Format(time.TimeOnly))
+		var err error
+		if lazy {
+			err = t.loadProcessesLazy()
+		} else {
+			err = t.loadProcessesSampled()
+		}
+		if err != nil {
+			return err
+		}
+		if t.config.IO {
+			io.update(t)
+		}
+		if err := t.redraw(header, churn.update(t), args); err != nil {
+			return err
+		}
+		hooks.update(t)
+
+		if t.config.FollowPid != -1 {
+			code, zombie := zombieExitStatus(t.config.FollowPid)
+			if zombie {
+				fmt.Fprintf(os.Stderr, "process %d exited with status %d\n", t.config.FollowPid, code)
+				return &exitStatusError{Code: code}
+			}
+			if t.getPidIndex(t.config.FollowPid) == -1 {
+				fmt.Fprintf(os.Stderr, "process %d is gone, exit status unknown\n", t.config.FollowPid)
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			lazy = false
+		case <-changes:
+			// between full refreshes only new processes are read in detail
+			lazy = t.config.WatchStrategy == "fast"
+		}
+	}
+}
+
+// watchHooks runs the --on-new and --on-exit commands for the printed
+// processes appearing and disappearing between refreshes
+type watchHooks struct {
+	printed map[procKey]Process
+}
+
+// update compares the printed processes with the previous refresh, the
+// first one only sets what is already there
+func (h *watchHooks) update(t *Tree) {
+	if t.config.OnNew == "" && t.config.OnExit == "" {
+		return
+	}
+	current := map[procKey]Process{}
+	for _, p := range t.procs {
+		if p.Print && !p.Thread && !p.Group && p.PID != myPID {
+			current[procKey{p.PID, p.StartTime}] = p
+		}
+	}
+	if h.printed != nil {
+		runHooks(t.config.OnNew, current, h.printed)
+		runHooks(t.config.OnExit, h.printed, current)
+	}
+	h.printed = current
+}
+
+// runHooks runs command for the processes of procs missing from others,
+// by pid order. The commands run in the background, their output goes to
+// stderr so it can be sent away from the screen
+func runHooks(command string, procs, others map[procKey]Process) {
+	if command == "" {
+		return
+	}
+	var keys []procKey
+	for k := range procs {
+		if _, ok := others[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b procKey) int { return a.PID - b.PID })
+
+	for _, k := range keys {
+		p := procs[k]
+		expanded := strings.NewReplacer(
+			"{}", strconv.Itoa(p.PID),
+			"{pid}", strconv.Itoa(p.PID),
+			"{ppid}", strconv.Itoa(p.PPID),
+			"{user}", shellQuote(p.Owner),
+			"{name}", shellQuote(processName(p)),
+			"{cmd}", shellQuote(p.Cmd),
+		).Replace(command)
+
+		cmd := exec.Command("sh", "-c", expanded)
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", expanded)
+		}
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		if err := cmd.Start(); err != nil {
+			log.Warnf("%s: %v", expanded, err)
+			continue
+		}
+		go func() {
+			if err := cmd.Wait(); err != nil {
+				log.Warnf("%s: %v", expanded, err)
+			}
+		}()
+	}
+}
+
+// shellQuote quotes a value substituted in a hook command, so command
+// lines can't inject commands of their own
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return cmdQuote(s)
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cmdQuote quotes s for cmd.exe. Quotes don't stop it from expanding
+// %VAR%, so every % is left out of them and follows a ^, which makes the
+// name it would start one no variable has, and is dropped once the
+// variables are expanded
+func cmdQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `""`)
+	return `"` + strings.ReplaceAll(s, "%", `"^%"`) + `"`
+}
@@ -0,0 +1,329 @@
+package pstree
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whereStrings are the text fields of a --where expression
+var whereStrings = map[string]func(*Process) string{
+	"user":      func(p *Process) string { return p.Owner },
+	"cmd":       func(p *Process) string { return p.Cmd },
+	"comm":      func(p *Process) string { return p.Comm },
+	"exe":       func(p *Process) string { return p.Exe },
+	"state":     func(p *Process) string { return p.State },
+	"tty":       func(p *Process) string { return p.TTY },
+	"container": func(p *Process) string { return p.Container },
+	"pod":       func(p *Process) string { return p.Pod },
+	"unit":      func(p *Process) string { return processUnit(*p) },
+}
+
+// whereNumbers are the numeric fields of a --where expression
+var whereNumbers = map[string]func(*Process) float64{
+	"pid":     func(p *Process) float64 { return float64(p.PID) },
+	"ppid":    func(p *Process) float64 { return float64(p.PPID) },
+	"pgid":    func(p *Process) float64 { return float64(p.PGID) },
+	"sid":     func(p *Process) float64 { return float64(p.SID) },
+	"uid":     func(p *Process) float64 { return float64(p.UID) },
+	"threads": func(p *Process) float64 { return float64(p.ThreadCount) },
+	"rss":     func(p *Process) float64 { return float64(p.RSS) },
+	"cpu":     func(p *Process) float64 { return p.CPUPercent },
+	"jail":    func(p *Process) float64 { return float64(p.Jail) },
+	"zone":    func(p *Process) float64 { return float64(p.Zone) },
+}
+
+// compileWhere parses the --where expression, e.g.
+//
+//	user == "www-data" && cmd =~ "php" && rss > 200MB
+//
+// comparisons on fields are combined with &&, ||, ! and parentheses
+func (t *Tree) compileWhere() error {
+	t.whereFilter, t.whereCPU, t.whereExe, t.whereCmd = nil, false, false, false
+	if t.config.Where == "" {
+		return nil
+	}
+	tokens, err := whereTokens(t.config.Where)
+	if err != nil {
+		return fmt.Errorf("--where: %w", err)
+	}
+	parser := &whereParser{tokens: tokens, ignoreCase: t.config.IgnoreCase}
+	filter, err := parser.or()
+	if err == nil && parser.pos < len(tokens) {
+		err = fmt.Errorf("unexpected %q", tokens[parser.pos].text)
+	}
+	if err != nil {
+		return fmt.Errorf("--where: %w", err)
+	}
+	t.whereFilter = filter
+	t.whereCPU = parser.cpu
+	t.whereExe = parser.exe
+	t.whereCmd = parser.cmd
+	return nil
+}
+
+// whereToken is a lexical token, quoted strings are kept apart from the
+// words and operators
+type whereToken struct {
+	text   string
+	quoted bool
+}
+
+// whereOperators are tried in order, longest first
+var whereOperators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!", "(", ")"}
+
+// whereTokens splits an expression into tokens
+func whereTokens(s string) ([]whereToken, error) {
+	var tokens []whereToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			literal := s[i : end+1]
+			if c == '\'' {
+				literal = doubleQuoted(s[i+1 : end])
+			}
+			text, err := strconv.Unquote(literal)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, whereToken{text: text, quoted: true})
+			i = end + 1
+		default:
+			op := ""
+			for _, candidate := range whereOperators {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, whereToken{text: op})
+				i += len(op)
+				continue
+			}
+			// words may hold letters of any script, e.g. user == josé
+			end := i
+			for end < len(s) {
+				r, size := utf8.DecodeRuneInString(s[end:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-/:%", r) {
+					break
+				}
+				end += size
+			}
+			if end == i {
+				r, _ := utf8.DecodeRuneInString(s[i:])
+				return nil, fmt.Errorf("unexpected %q at %d", r, i)
+			}
+			tokens = append(tokens, whereToken{text: s[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// doubleQuoted turns the inside of a single-quoted string into a double
+// quoted one, so both kinds of strings take the same escapes
+func doubleQuoted(s string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '\'':
+			quoted.WriteByte('\'')
+			i++
+		case s[i] == '\\' && i+1 < len(s):
+			quoted.WriteString(s[i : i+2])
+			i++
+		case s[i] == '"':
+			quoted.WriteString(`\"`)
+		default:
+			quoted.WriteByte(s[i])
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// whereParser is a recursive descent parser producing the filter closure
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+	// regexps match regardless of case, --ignore-case
+	ignoreCase bool
+	// the expression reads the cpu usage, the executable path or the
+	// command line
+	cpu bool
+	exe bool
+	cmd bool
+}
+
+// next returns the next token, empty at the end of the expression
+func (p *whereParser) next() whereToken {
+	if p.pos >= len(p.tokens) {
+		return whereToken{}
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+// accept consumes the next token if it is the operator op
+func (p *whereParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// or parses and-expressions separated by ||
+func (p *whereParser) or() (func(*Process) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(proc *Process) bool { return l(proc) || right(proc) }
+	}
+	return left, nil
+}
+
+// and parses unary expressions separated by &&
+func (p *whereParser) and() (func(*Process) bool, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(proc *Process) bool { return l(proc) && right(proc) }
+	}
+	return left, nil
+}
+
+// unary parses a negation, a parenthesized expression or a comparison
+func (p *whereParser) unary() (func(*Process) bool, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(proc *Process) bool { return !operand(proc) }, nil
+	}
+	if p.accept("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+// comparison parses FIELD OP VALUE
+func (p *whereParser) comparison() (func(*Process) bool, error) {
+	field := p.next()
+	if field.text == "" || field.quoted {
+		return nil, fmt.Errorf("expected a field name")
+	}
+	op := p.next()
+	if op.quoted || !slices.Contains([]string{"==", "!=", "=~", "!~", "<", "<=", ">", ">="}, op.text) {
+		return nil, fmt.Errorf("expected an operator after %s", field.text)
+	}
+	value := p.next()
+	if value.text == "" && !value.quoted {
+		return nil, fmt.Errorf("expected a value after %s %s", field.text, op.text)
+	}
+
+	if get, ok := whereStrings[field.text]; ok {
+		switch field.text {
+		case "exe":
+			p.exe = true
+		case "cmd":
+			p.cmd = true
+		}
+		switch op.text {
+		case "==":
+			return func(proc *Process) bool { return get(proc) == value.text }, nil
+		case "!=":
+			return func(proc *Process) bool { return get(proc) != value.text }, nil
+		case "=~", "!~":
+			expr := value.text
+			if p.ignoreCase {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", value.text, err)
+			}
+			negate := op.text == "!~"
+			return func(proc *Process) bool { return re.MatchString(get(proc)) != negate }, nil
+		}
+		return nil, fmt.Errorf("%s is not numeric", field.text)
+	}
+
+	get, ok := whereNumbers[field.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field.text)
+	}
+	var number float64
+	if field.text == "rss" {
+		size, err := parseSize(value.text)
+		if err != nil {
+			return nil, err
+		}
+		number = float64(size)
+	} else {
+		var err error
+		if number, err = strconv.ParseFloat(strings.TrimSuffix(value.text, "%"), 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", value.text)
+		}
+	}
+	if field.text == "cpu" {
+		p.cpu = true
+	}
+	switch op.text {
+	case "==":
+		return func(proc *Process) bool { return get(proc) == number }, nil
+	case "!=":
+		return func(proc *Process) bool { return get(proc) != number }, nil
+	case "<":
+		return func(proc *Process) bool { return get(proc) < number }, nil
+	case "<=":
+		return func(proc *Process) bool { return get(proc) <= number }, nil
+	case ">":
+		return func(proc *Process) bool { return get(proc) > number }, nil
+	case ">=":
+		return func(proc *Process) bool { return get(proc) >= number }, nil
+	}
+	return nil, fmt.Errorf("%s cannot be matched with %s", field.text, op.text)
+}